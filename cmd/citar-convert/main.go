@@ -0,0 +1,199 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/danieldk/citar/cmd/common"
+	"github.com/danieldk/citar/model"
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] input output\n\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Formats: gob (citar-train output), json (single file), tsv (directory),")
+		fmt.Fprintln(os.Stderr, "         tnt (TnT parameter files, the path is used as the prefix of .lex and .123)")
+		fmt.Fprintln(os.Stderr)
+		flag.PrintDefaults()
+	}
+}
+
+var from = flag.String("from", "gob", "input format")
+var to = flag.String("to", "json", "output format")
+var boundaryTag = flag.String("boundary-tag", "", "sentence-final tag used to add sentence boundaries to TnT models")
+var verify = flag.Bool("verify", false, "read back the output and verify that it is equal to the input")
+
+type modelReader func(filename string) (model.Model, error)
+
+type modelWriter func(m model.Model, filename string) error
+
+var readers = map[string]modelReader{
+	"gob":  readGob,
+	"json": readJSON,
+	"tsv":  model.ReadTSV,
+	"tnt":  readTnT,
+}
+
+var writers = map[string]modelWriter{
+	"gob":  writeGob,
+	"json": writeJSON,
+	"tsv":  model.Model.WriteTSV,
+	"tnt":  writeTnT,
+}
+
+func main() {
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	read, ok := readers[*from]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown input format: %s\n", *from)
+		os.Exit(1)
+	}
+
+	write, ok := writers[*to]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown output format: %s\n", *to)
+		os.Exit(1)
+	}
+
+	m, err := read(flag.Arg(0))
+	common.ExitIfError("Cannot read model", err)
+
+	err = write(m, flag.Arg(1))
+	common.ExitIfError("Cannot write model", err)
+
+	if *verify {
+		converted, err := readers[*to](flag.Arg(1))
+		common.ExitIfError("Cannot read converted model", err)
+
+		equal, err := modelsEqual(m, converted)
+		common.ExitIfError("Cannot compare models", err)
+
+		if !equal {
+			fmt.Fprintln(os.Stderr, "Converted model differs from the input model")
+			os.Exit(1)
+		}
+	}
+}
+
+// modelsEqual compares two models using their (deterministic) JSON
+// serializations.
+func modelsEqual(m1, m2 model.Model) (bool, error) {
+	var buf1, buf2 bytes.Buffer
+
+	if err := m1.WriteJSON(&buf1); err != nil {
+		return false, err
+	}
+
+	if err := m2.WriteJSON(&buf2); err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(buf1.Bytes(), buf2.Bytes()), nil
+}
+
+func readGob(filename string) (model.Model, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return model.Model{}, err
+	}
+	defer f.Close()
+
+	var m model.Model
+	err = gob.NewDecoder(bufio.NewReader(f)).Decode(&m)
+	return m, err
+}
+
+func writeGob(m model.Model, filename string) error {
+	return writeFile(filename, func(w *bufio.Writer) error {
+		return gob.NewEncoder(w).Encode(m)
+	})
+}
+
+func readJSON(filename string) (model.Model, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return model.Model{}, err
+	}
+	defer f.Close()
+
+	return model.ReadJSON(bufio.NewReader(f))
+}
+
+func writeJSON(m model.Model, filename string) error {
+	return writeFile(filename, func(w *bufio.Writer) error {
+		return m.WriteJSON(w)
+	})
+}
+
+func readTnT(prefix string) (model.Model, error) {
+	lexFile, err := os.Open(prefix + ".lex")
+	if err != nil {
+		return model.Model{}, err
+	}
+	defer lexFile.Close()
+
+	ngramFile, err := os.Open(prefix + ".123")
+	if err != nil {
+		return model.Model{}, err
+	}
+	defer ngramFile.Close()
+
+	return model.ReadTnT(lexFile, ngramFile, *boundaryTag)
+}
+
+func writeTnT(m model.Model, prefix string) error {
+	lexFile, err := os.Create(prefix + ".lex")
+	if err != nil {
+		return err
+	}
+	defer lexFile.Close()
+
+	ngramFile, err := os.Create(prefix + ".123")
+	if err != nil {
+		return err
+	}
+	defer ngramFile.Close()
+
+	if err := m.WriteTnT(lexFile, ngramFile); err != nil {
+		return err
+	}
+
+	if err := lexFile.Close(); err != nil {
+		return err
+	}
+
+	return ngramFile.Close()
+}
+
+func writeFile(filename string, write func(w *bufio.Writer) error) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if err := write(w); err != nil {
+		return err
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
@@ -13,10 +13,8 @@ import (
 	"runtime/pprof"
 
 	"github.com/danieldk/citar/cmd/common"
-	"github.com/danieldk/citar/model"
 	"github.com/danieldk/citar/tagger"
 	"github.com/danieldk/citar/trigrams"
-	"github.com/danieldk/citar/words"
 	"github.com/danieldk/conllx"
 )
 
@@ -29,7 +27,8 @@ func init() {
 
 var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file")
 var nFolds = flag.Int("nfolds", 10, "number of cross-validation folds")
-var closedClassFilename = flag.String("closed-class", "", "file with closed-class tags")
+var closedClassFilename = flag.String("closed-class", "", "file with closed-class tags, in addition to the closed_class section")
+var tagMappingFilename = flag.String("tag-mapping", "", "file with a mapping of fine to coarse tags, to report coarse accuracy")
 
 func trainFolds(testFold int) conllx.FoldSet {
 	folds := make(conllx.FoldSet)
@@ -62,6 +61,14 @@ func main() {
 	var knownIncorrect uint
 	var unknownCorrect uint
 	var unknownIncorrect uint
+	var coarseKnownCorrect uint
+	var coarseKnownIncorrect uint
+	var coarseUnknownCorrect uint
+	var coarseUnknownIncorrect uint
+	var posKnownCorrect uint
+	var posKnownIncorrect uint
+	var posUnknownCorrect uint
+	var posUnknownIncorrect uint
 
 	if *cpuprofile != "" {
 		f, err := os.Create(*cpuprofile)
@@ -72,31 +79,36 @@ func main() {
 
 	closedClass := common.MustLoadClosedClass(*closedClassFilename)
 	substitutions := common.MustLoadSubstitutions(config.Substitutions)
+	tagMapping := common.MustLoadTagMapping(*tagMappingFilename)
+
+	if *tune {
+		tuneSuffixHandler(config, closedClass, substitutions)
+		return
+	}
+
+	if *compareSmoothing {
+		compareLexiconSmoothing(config, closedClass, substitutions)
+		return
+	}
 
 	for fold := 0; fold < *nFolds; fold++ {
-		fc := model.NewFrequencyCollector()
+		fc := config.FrequencyCollector(nil)
 
 		err := processFolds(flag.Arg(1), trainFolds(fold), func(sent []conllx.Token) error {
 			return fc.Process(sent)
 		})
 		common.ExitIfError("Error processing training folds", err)
 
-		model := fc.ModelWithClosedClass(closedClass)
+		model, err := config.TrainedModel(fc, closedClass)
+		common.ExitIfError("Cannot determine closed-class tags", err)
 
-		sh, err := config.UnknownWordHandler(model)
-		common.ExitIfError("Could not construct unknown word handler", err)
-
-		var lh words.WordHandler
-		if len(substitutions) == 0 {
-			lh = words.NewLexiconWithFallback(model.WordTagFreqs(), model.UnigramFreqs(), sh)
-		} else {
-			lh = words.NewSubstLexiconWithFallback(words.NewLexicon(model.WordTagFreqs(), model.UnigramFreqs()), sh, substitutions)
-		}
+		lh, err := config.WordHandler(model, substitutions)
+		common.ExitIfError("Could not construct word handler", err)
 
 		lim := trigrams.NewLinearInterpolationModel(model)
 		tagger := tagger.NewHMMTagger(model, lh, lim, 1000.0)
 
-		eval := common.NewEvaluator(tagger, model)
+		eval := common.NewEvaluatorWithTagMapping(tagger, model, tagMapping)
 
 		err = processFolds(flag.Arg(1), conllx.FoldSet{fold: nil}, func(sent []conllx.Token) error {
 			return eval.Process(sent)
@@ -105,11 +117,27 @@ func main() {
 
 		fmt.Printf("Fold %d accuracy: %2f (known: %2f, unknown: %2f)\n", fold, eval.Accuracy(),
 			eval.KnownAccuracy(), eval.UnknownAccuracy())
+		if len(config.Features) != 0 {
+			fmt.Printf("Fold %d POS accuracy: %2f (known: %2f, unknown: %2f)\n", fold,
+				eval.POSAccuracy(), eval.POSKnownAccuracy(), eval.POSUnknownAccuracy())
+		}
+		if tagMapping != nil {
+			fmt.Printf("Fold %d coarse accuracy: %2f (known: %2f, unknown: %2f)\n", fold,
+				eval.CoarseAccuracy(), eval.CoarseKnownAccuracy(), eval.CoarseUnknownAccuracy())
+		}
 
 		knownCorrect += eval.KnownCorrect()
 		knownIncorrect += eval.KnownIncorrect()
 		unknownCorrect += eval.UnknownCorrect()
 		unknownIncorrect += eval.UnknownIncorrect()
+		coarseKnownCorrect += eval.CoarseKnownCorrect()
+		coarseKnownIncorrect += eval.CoarseKnownIncorrect()
+		coarseUnknownCorrect += eval.CoarseUnknownCorrect()
+		coarseUnknownIncorrect += eval.CoarseUnknownIncorrect()
+		posKnownCorrect += eval.POSKnownCorrect()
+		posKnownIncorrect += eval.POSKnownIncorrect()
+		posUnknownCorrect += eval.POSUnknownCorrect()
+		posUnknownIncorrect += eval.POSUnknownIncorrect()
 	}
 
 	accuracy := float64(knownCorrect+unknownCorrect) /
@@ -120,6 +148,26 @@ func main() {
 	fmt.Printf("Overall accuracy: %2f (known: %2f, unknown: %2f)\n", accuracy,
 		knownAccuracy, unknownAccuracy)
 
+	if len(config.Features) != 0 {
+		accuracy = float64(posKnownCorrect+posUnknownCorrect) /
+			float64(posKnownCorrect+posUnknownCorrect+posKnownIncorrect+posUnknownIncorrect)
+		knownAccuracy = float64(posKnownCorrect) / float64(posKnownCorrect+posKnownIncorrect)
+		unknownAccuracy = float64(posUnknownCorrect) / float64(posUnknownCorrect+posUnknownIncorrect)
+
+		fmt.Printf("Overall POS accuracy: %2f (known: %2f, unknown: %2f)\n", accuracy,
+			knownAccuracy, unknownAccuracy)
+	}
+
+	if tagMapping != nil {
+		accuracy = float64(coarseKnownCorrect+coarseUnknownCorrect) /
+			float64(coarseKnownCorrect+coarseUnknownCorrect+coarseKnownIncorrect+coarseUnknownIncorrect)
+		knownAccuracy = float64(coarseKnownCorrect) / float64(coarseKnownCorrect+coarseKnownIncorrect)
+		unknownAccuracy = float64(coarseUnknownCorrect) / float64(coarseUnknownCorrect+coarseUnknownIncorrect)
+
+		fmt.Printf("Overall coarse accuracy: %2f (known: %2f, unknown: %2f)\n", accuracy,
+			knownAccuracy, unknownAccuracy)
+	}
+
 }
 
 func processFolds(filename string, folds conllx.FoldSet, fun func(sent []conllx.Token) error) error {
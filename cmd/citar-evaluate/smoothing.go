@@ -0,0 +1,49 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/danieldk/citar/cmd/common"
+	"github.com/danieldk/citar/model"
+	"github.com/danieldk/citar/words"
+)
+
+var compareSmoothing = flag.Bool("compare-smoothing", false, "report the known word accuracy with and without lexicon smoothing")
+
+// compareLexiconSmoothing reports the known word accuracy of the
+// configuration with and without the lexicon_smoothing section, so that
+// the effect of extending the tags of rare known words can be measured.
+// Both configurations are evaluated on the same models.
+func compareLexiconSmoothing(config *common.CitarConfig, closedClass model.ClosedClassSet,
+	substitutions []words.Substitution) {
+	if config.LexiconSmoothing.MaxFreq == 0 {
+		fmt.Fprintln(os.Stderr, "Lexicon smoothing is disabled (max_freq is 0), there is nothing to compare")
+		os.Exit(1)
+	}
+
+	folds := loadEvalFolds(config, closedClass)
+
+	knownCounts := func(eval *common.Evaluator) (uint, uint) {
+		return eval.KnownCorrect(), eval.KnownIncorrect()
+	}
+
+	smoothed, err := foldsAccuracy(*config, folds, substitutions, knownCounts)
+	common.ExitIfError("Could not evaluate configuration", err)
+
+	unsmoothedConfig := *config
+	unsmoothedConfig.LexiconSmoothing.MaxFreq = 0
+	unsmoothed, err := foldsAccuracy(unsmoothedConfig, folds, substitutions, knownCounts)
+	common.ExitIfError("Could not evaluate configuration", err)
+
+	fmt.Printf("Known accuracy without lexicon smoothing: %2f\n", unsmoothed)
+	fmt.Printf("Known accuracy with lexicon smoothing: %2f (max_freq: %d, max_tags: %d, weight: %g)\n",
+		smoothed, config.LexiconSmoothing.MaxFreq, config.LexiconSmoothing.MaxTags,
+		config.LexiconSmoothing.Weight)
+	fmt.Printf("Difference: %+f\n", smoothed-unsmoothed)
+}
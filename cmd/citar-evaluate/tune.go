@@ -0,0 +1,249 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/danieldk/citar/cmd/common"
+	"github.com/danieldk/citar/model"
+	"github.com/danieldk/citar/tagger"
+	"github.com/danieldk/citar/trigrams"
+	"github.com/danieldk/citar/words"
+	"github.com/danieldk/conllx"
+)
+
+var tune = flag.Bool("tune", false, "search the suffix handler parameters that give the best unknown word accuracy")
+var tuneSuffixLens = flag.String("tune-suffix-lens", "1,2,3,4,5", "maximum suffix lengths to try when tuning")
+var tuneMaxFreqs = flag.String("tune-max-freqs", "1,2,4,8,16,32", "maximum frequencies to try when tuning")
+var tunePasses = flag.Int("tune-passes", 3, "maximum number of passes over the parameters when tuning")
+
+// evalFold stores the model that is trained on the training folds and
+// the sentences of the test fold.
+type evalFold struct {
+	model model.Model
+	test  [][]conllx.Token
+}
+
+// suffixParam is a tunable parameter of the suffix handler.
+type suffixParam struct {
+	name   string
+	values []int
+	get    func(c words.SuffixHandlerConfig) int
+	set    func(c *words.SuffixHandlerConfig, value int)
+}
+
+// tuneSuffixHandler searches the maximum suffix length and the maximum
+// frequencies of the word classes of the suffix handler that give the
+// highest unknown word accuracy. The search optimizes one parameter at a
+// time, keeping the other parameters fixed, until a pass over all
+// parameters does not improve the accuracy.
+func tuneSuffixHandler(config *common.CitarConfig, closedClass model.ClosedClassSet,
+	substitutions []words.Substitution) {
+	if !config.UsesSuffixHandler() {
+		fmt.Fprintf(os.Stderr, "The unknown word handler does not use the suffix handler: %s\n",
+			config.UnknownHandler)
+		os.Exit(1)
+	}
+
+	suffixLens, err := parseIntList(*tuneSuffixLens)
+	common.ExitIfError("Invalid suffix lengths", err)
+
+	maxFreqs, err := parseIntList(*tuneMaxFreqs)
+	common.ExitIfError("Invalid maximum frequencies", err)
+
+	folds := loadEvalFolds(config, closedClass)
+	params := suffixParams(config.SuffixHandler, suffixLens, maxFreqs)
+
+	best := *config
+	bestAccuracy, err := unknownAccuracy(best, folds, substitutions)
+	common.ExitIfError("Could not evaluate configuration", err)
+	fmt.Printf("Initial unknown accuracy: %2f\n", bestAccuracy)
+
+	for pass := 0; pass < *tunePasses; pass++ {
+		improved := false
+
+		for _, param := range params {
+			current := param.get(best.SuffixHandler)
+
+			for _, value := range param.values {
+				if value == current {
+					continue
+				}
+
+				candidate := best
+				param.set(&candidate.SuffixHandler, value)
+				if err := candidate.SuffixHandler.Validate(); err != nil {
+					continue
+				}
+
+				accuracy, err := unknownAccuracy(candidate, folds, substitutions)
+				common.ExitIfError("Could not evaluate configuration", err)
+				fmt.Printf("Pass %d, %s = %d: unknown accuracy: %2f\n", pass, param.name, value, accuracy)
+
+				if accuracy > bestAccuracy {
+					best = candidate
+					bestAccuracy = accuracy
+					improved = true
+				}
+			}
+		}
+
+		if !improved {
+			break
+		}
+	}
+
+	fmt.Printf("Best unknown accuracy: %2f\n\n", bestAccuracy)
+
+	section := struct {
+		SuffixHandler words.SuffixHandlerConfig `toml:"suffix_handler"`
+	}{best.SuffixHandler}
+	err = toml.NewEncoder(os.Stdout).Encode(section)
+	common.ExitIfError("Cannot write configuration", err)
+}
+
+// suffixParams returns the tunable parameters of the suffix handler: the
+// maximum suffix length and the maximum frequency of each word class.
+func suffixParams(config words.SuffixHandlerConfig, suffixLens, maxFreqs []int) []suffixParam {
+	params := []suffixParam{
+		{
+			name:   "max_suffix_len",
+			values: suffixLens,
+			get:    func(c words.SuffixHandlerConfig) int { return c.MaxSuffixLen },
+			set:    func(c *words.SuffixHandlerConfig, value int) { c.MaxSuffixLen = value },
+		},
+	}
+
+	if len(config.WordClasses) == 0 {
+		return append(params,
+			suffixParam{
+				name:   "upper_max_freq",
+				values: maxFreqs,
+				get:    func(c words.SuffixHandlerConfig) int { return c.UpperMaxFreq },
+				set:    func(c *words.SuffixHandlerConfig, value int) { c.UpperMaxFreq = value },
+			},
+			suffixParam{
+				name:   "lower_max_freq",
+				values: maxFreqs,
+				get:    func(c words.SuffixHandlerConfig) int { return c.LowerMaxFreq },
+				set:    func(c *words.SuffixHandlerConfig, value int) { c.LowerMaxFreq = value },
+			},
+			suffixParam{
+				name:   "dash_max_freq",
+				values: maxFreqs,
+				get:    func(c words.SuffixHandlerConfig) int { return c.DashMaxFreq },
+				set:    func(c *words.SuffixHandlerConfig, value int) { c.DashMaxFreq = value },
+			},
+			suffixParam{
+				name:   "cardinal_max_freq",
+				values: maxFreqs,
+				get:    func(c words.SuffixHandlerConfig) int { return c.CardinalMaxFreq },
+				set:    func(c *words.SuffixHandlerConfig, value int) { c.CardinalMaxFreq = value },
+			})
+	}
+
+	for i, class := range config.WordClasses {
+		idx := i
+		params = append(params, suffixParam{
+			name:   fmt.Sprintf("%s max_freq", class.Name),
+			values: maxFreqs,
+			get:    func(c words.SuffixHandlerConfig) int { return c.WordClasses[idx].MaxFreq },
+			set: func(c *words.SuffixHandlerConfig, value int) {
+				// Copy the classes, they are shared with other configurations.
+				classes := append([]words.WordClassConfig(nil), c.WordClasses...)
+				classes[idx].MaxFreq = value
+				c.WordClasses = classes
+			},
+		})
+	}
+
+	return params
+}
+
+// loadEvalFolds trains a model for each fold on the remaining folds and
+// reads the sentences of the fold.
+func loadEvalFolds(config *common.CitarConfig, closedClass model.ClosedClassSet) []evalFold {
+	folds := make([]evalFold, *nFolds)
+
+	for fold := 0; fold < *nFolds; fold++ {
+		fc := config.FrequencyCollector(nil)
+
+		err := processFolds(flag.Arg(1), trainFolds(fold), func(sent []conllx.Token) error {
+			return fc.Process(sent)
+		})
+		common.ExitIfError("Error processing training folds", err)
+
+		folds[fold].model, err = config.TrainedModel(fc, closedClass)
+		common.ExitIfError("Cannot determine closed-class tags", err)
+
+		err = processFolds(flag.Arg(1), conllx.FoldSet{fold: nil}, func(sent []conllx.Token) error {
+			// The reader reuses the token slice of a sentence.
+			folds[fold].test = append(folds[fold].test, append([]conllx.Token(nil), sent...))
+			return nil
+		})
+		common.ExitIfError("Error processing testing fold", err)
+	}
+
+	return folds
+}
+
+// unknownAccuracy returns the unknown word accuracy of a configuration
+// over all folds.
+func unknownAccuracy(config common.CitarConfig, folds []evalFold,
+	substitutions []words.Substitution) (float64, error) {
+	return foldsAccuracy(config, folds, substitutions, func(eval *common.Evaluator) (uint, uint) {
+		return eval.UnknownCorrect(), eval.UnknownIncorrect()
+	})
+}
+
+// foldsAccuracy tags the test sentences of all folds using a configuration
+// and returns the accuracy over the counts that are selected by counts.
+func foldsAccuracy(config common.CitarConfig, folds []evalFold, substitutions []words.Substitution,
+	counts func(eval *common.Evaluator) (correct, incorrect uint)) (float64, error) {
+	var correct, incorrect uint
+
+	for _, fold := range folds {
+		lh, err := config.WordHandler(fold.model, substitutions)
+		if err != nil {
+			return 0, err
+		}
+
+		lim := trigrams.NewLinearInterpolationModel(fold.model)
+		eval := common.NewEvaluator(tagger.NewHMMTagger(fold.model, lh, lim, 1000.0), fold.model)
+
+		for _, sent := range fold.test {
+			if err := eval.Process(sent); err != nil {
+				return 0, err
+			}
+		}
+
+		foldCorrect, foldIncorrect := counts(eval)
+		correct += foldCorrect
+		incorrect += foldIncorrect
+	}
+
+	return float64(correct) / float64(correct+incorrect), nil
+}
+
+func parseIntList(list string) ([]int, error) {
+	var values []int
+
+	for _, field := range strings.Split(list, ",") {
+		value, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	return values, nil
+}
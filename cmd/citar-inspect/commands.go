@@ -0,0 +1,213 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/danieldk/citar/cmd/common"
+	"github.com/danieldk/citar/model"
+	"github.com/danieldk/citar/trigrams"
+	"github.com/danieldk/citar/words"
+)
+
+type tagFreq struct {
+	tag  model.Tag
+	freq int
+}
+
+func sortedTagFreqs(freqs map[model.Tag]int, numberer *model.StringNumberer) []tagFreq {
+	sorted := make([]tagFreq, 0, len(freqs))
+	for tag, freq := range freqs {
+		sorted = append(sorted, tagFreq{tag, freq})
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].freq != sorted[j].freq {
+			return sorted[i].freq > sorted[j].freq
+		}
+
+		return formatTag(numberer, sorted[i].tag) < formatTag(numberer, sorted[j].tag)
+	})
+
+	return sorted
+}
+
+type tagProb struct {
+	tag  model.Tag
+	prob float64
+}
+
+func sortedTagProbs(probs map[model.Tag]float64, numberer *model.StringNumberer) []tagProb {
+	sorted := make([]tagProb, 0, len(probs))
+	for tag, prob := range probs {
+		sorted = append(sorted, tagProb{tag, prob})
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].prob != sorted[j].prob {
+			return sorted[i].prob > sorted[j].prob
+		}
+
+		return formatTag(numberer, sorted[i].tag) < formatTag(numberer, sorted[j].tag)
+	})
+
+	return sorted
+}
+
+func writeTagProbs(w *bufio.Writer, numberer *model.StringNumberer, probs map[model.Tag]float64) {
+	sorted := sortedTagProbs(probs, numberer)
+	for _, tp := range sorted[:limitEntries(len(sorted))] {
+		fmt.Fprintf(w, "\t%s\t%.4f\n", formatTag(numberer, tp.tag), tp.prob)
+	}
+}
+
+func listTags(w *bufio.Writer, config *common.CitarConfig, m model.Model, args []string) {
+	freqs := make(map[model.Tag]int)
+	for unigram, freq := range m.UnigramFreqs() {
+		freqs[unigram.T1] = freq
+	}
+
+	numberer := m.TagNumberer()
+	sorted := sortedTagFreqs(freqs, numberer)
+	for _, tf := range sorted[:limitEntries(len(sorted))] {
+		closed := ""
+		if m.IsClosedClassTag(tf.tag) {
+			closed = "\tclosed"
+		}
+
+		fmt.Fprintf(w, "%s\t%d%s\n", formatTag(numberer, tf.tag), tf.freq, closed)
+	}
+}
+
+func showWords(w *bufio.Writer, config *common.CitarConfig, m model.Model, args []string) {
+	substitutions := common.MustLoadSubstitutions(config.Substitutions)
+
+	lexicon := words.NewLexicon(m.WordTagFreqs(), m.UnigramFreqs())
+
+	var lh words.WordHandler = lexicon
+	if len(substitutions) != 0 {
+		lh = words.NewSubstLexicon(lexicon, substitutions)
+	}
+
+	numberer := m.TagNumberer()
+
+	for _, word := range args {
+		fmt.Fprintf(w, "%s\n", word)
+
+		if normalized := m.Normalization().Normalize(word); normalized != word {
+			fmt.Fprintf(w, "  normalized: %s\n", normalized)
+			word = normalized
+		}
+
+		if freqs, ok := m.WordTagFreqs()[word]; ok {
+			fmt.Fprintln(w, "  frequencies:")
+			for _, tf := range sortedTagFreqs(freqs, numberer) {
+				fmt.Fprintf(w, "\t%s\t%d\n", formatTag(numberer, tf.tag), tf.freq)
+			}
+		} else {
+			fmt.Fprintln(w, "  not in training data")
+		}
+
+		probs := lh.TagProbs(word)
+		if len(probs) == 0 {
+			fmt.Fprintln(w, "  unknown to the lexicon")
+			continue
+		}
+
+		fmt.Fprintln(w, "  lexicon log P(w|t):")
+		writeTagProbs(w, numberer, probs)
+	}
+}
+
+func showSuffix(w *bufio.Writer, config *common.CitarConfig, m model.Model, args []string) {
+	sh := words.NewSuffixHandler(config.SuffixHandler, m)
+	numberer := m.TagNumberer()
+
+	for _, word := range args {
+		if len(word) == 0 {
+			continue
+		}
+
+		word = m.Normalization().Normalize(word)
+
+		fmt.Fprintf(w, "%s (class: %s)\n", word, sh.WordClass(word))
+		writeTagProbs(w, numberer, sh.TagProbs(word))
+	}
+}
+
+func showCompounds(w *bufio.Writer, config *common.CitarConfig, m model.Model, args []string) {
+	ch := words.NewCompoundHandler(config.CompoundHandler, m,
+		words.NewSuffixHandler(config.SuffixHandler, m))
+	numberer := m.TagNumberer()
+
+	for _, word := range args {
+		if len(word) == 0 {
+			continue
+		}
+
+		word = m.Normalization().Normalize(word)
+
+		if modifier, head, ok := ch.Split(word); ok {
+			fmt.Fprintf(w, "%s (modifier: %s, head: %s)\n", word, modifier, head)
+		} else {
+			fmt.Fprintf(w, "%s (no split, suffix handler)\n", word)
+		}
+
+		writeTagProbs(w, numberer, ch.TagProbs(word))
+	}
+}
+
+func showLambdas(w *bufio.Writer, config *common.CitarConfig, m model.Model, args []string) {
+	l1, l2, l3 := trigrams.NewLinearInterpolationModel(m).Lambdas()
+	fmt.Fprintf(w, "l1\t%f\nl2\t%f\nl3\t%f\n", l1, l2, l3)
+}
+
+type ngramFreq struct {
+	tags []model.Tag
+	freq int
+}
+
+func dumpNGrams(w *bufio.Writer, config *common.CitarConfig, m model.Model, args []string) {
+	order, err := strconv.Atoi(args[0])
+	if err != nil || order < 1 || order > 3 {
+		fmt.Fprintf(os.Stderr, "N-gram order should be 1, 2, or 3: %s\n", args[0])
+		os.Exit(1)
+	}
+
+	var ngrams []ngramFreq
+	switch order {
+	case 1:
+		for unigram, freq := range m.UnigramFreqs() {
+			ngrams = append(ngrams, ngramFreq{[]model.Tag{unigram.T1}, freq})
+		}
+	case 2:
+		for bigram, freq := range m.BigramFreqs() {
+			ngrams = append(ngrams, ngramFreq{[]model.Tag{bigram.T1, bigram.T2}, freq})
+		}
+	case 3:
+		for trigram, freq := range m.TrigramFreqs() {
+			ngrams = append(ngrams, ngramFreq{[]model.Tag{trigram.T1, trigram.T2, trigram.T3}, freq})
+		}
+	}
+
+	numberer := m.TagNumberer()
+
+	sort.Slice(ngrams, func(i, j int) bool {
+		if ngrams[i].freq != ngrams[j].freq {
+			return ngrams[i].freq > ngrams[j].freq
+		}
+
+		return formatTags(numberer, ngrams[i].tags...) < formatTags(numberer, ngrams[j].tags...)
+	})
+
+	for _, ngram := range ngrams[:limitEntries(len(ngrams))] {
+		fmt.Fprintf(w, "%s\t%d\n", formatTags(numberer, ngram.tags...), ngram.freq)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/danieldk/citar/cmd/common"
+	"github.com/danieldk/citar/model"
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] config command [args]\n\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Commands:")
+		for _, name := range commandNames() {
+			fmt.Fprintf(os.Stderr, "  %-9s %s\n", name, commands[name].description)
+		}
+		fmt.Fprintln(os.Stderr, "\nOptions:")
+		flag.PrintDefaults()
+	}
+}
+
+var limit = flag.Int("n", 0, "maximum number of entries to print (0: no limit)")
+
+type command struct {
+	description string
+	minArgs     int
+	run         func(w *bufio.Writer, config *common.CitarConfig, m model.Model, args []string)
+}
+
+var commands = map[string]command{
+	"tags":     {"list tags with their frequencies", 0, listTags},
+	"word":     {"show lexicon entries and emission probabilities of words", 1, showWords},
+	"suffix":   {"show suffix handler predictions for strings", 1, showSuffix},
+	"compound": {"show compound splits and compound handler predictions", 1, showCompounds},
+	"lambdas":  {"print the trigram interpolation weights", 0, showLambdas},
+	"ngrams":   {"dump the n-gram table of order 1, 2, or 3", 1, dumpNGrams},
+}
+
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func main() {
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	cmd, ok := commands[flag.Arg(1)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", flag.Arg(1))
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	args := flag.Args()[2:]
+	if len(args) < cmd.minArgs {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	config := common.MustParseConfig(flag.Arg(0))
+	m := common.MustLoadModel(config.Model)
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	cmd.run(w, config, m, args)
+}
+
+// formatTag returns a human-readable representation of a tag. Tags of
+// capitalized words are marked with a '/C' suffix.
+func formatTag(numberer *model.StringNumberer, tag model.Tag) string {
+	if tag.Capital {
+		return numberer.Label(tag.Tag) + "/C"
+	}
+
+	return numberer.Label(tag.Tag)
+}
+
+func formatTags(numberer *model.StringNumberer, tags ...model.Tag) string {
+	labels := make([]string, len(tags))
+	for i, tag := range tags {
+		labels[i] = formatTag(numberer, tag)
+	}
+
+	return strings.Join(labels, "\t")
+}
+
+// limitEntries returns the number of entries that should be printed
+// when there are n entries available.
+func limitEntries(n int) int {
+	if *limit > 0 && *limit < n {
+		return *limit
+	}
+
+	return n
+}
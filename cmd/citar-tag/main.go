@@ -6,18 +6,18 @@ package main
 
 import (
 	"bufio"
-	"encoding/gob"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"runtime/pprof"
+	"strings"
 
 	"github.com/danieldk/citar/cmd/common"
+	"github.com/danieldk/citar/lemma"
 	"github.com/danieldk/citar/model"
 	"github.com/danieldk/citar/tagger"
 	"github.com/danieldk/citar/trigrams"
-	"github.com/danieldk/citar/words"
 	"github.com/danieldk/conllx"
 )
 
@@ -29,6 +29,7 @@ func init() {
 }
 
 var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file")
+var tagMappingFilename = flag.String("tag-mapping", "", "file with a mapping of fine to coarse tags, to output coarse tags")
 
 func main() {
 	flag.Parse()
@@ -40,11 +41,8 @@ func main() {
 
 	config := common.MustParseConfig(flag.Arg(0))
 
-	modelFile, err := os.Open(config.Model)
-	common.ExitIfError("Cannot open model", err)
-	defer modelFile.Close()
-
 	substitutions := common.MustLoadSubstitutions(config.Substitutions)
+	tagMapping := common.MustLoadTagMapping(*tagMappingFilename)
 
 	inputFile := common.FileOrStdin(flag.Args(), 1)
 	defer inputFile.Close()
@@ -52,23 +50,23 @@ func main() {
 	outputFile := common.FileOrStdout(flag.Args(), 2)
 	defer outputFile.Close()
 
-	var model model.Model
-	decoder := gob.NewDecoder(modelFile)
-	err = decoder.Decode(&model)
-	common.ExitIfError("Could not load model", err)
-
-	sh, err := config.UnknownWordHandler(model)
-	common.ExitIfError("Could construct unknown word handler", err)
-
-	var lh words.WordHandler
-	if len(substitutions) == 0 {
-		lh = words.NewLexiconWithFallback(model.WordTagFreqs(), model.UnigramFreqs(), sh)
-	} else {
-		lh = words.NewSubstLexiconWithFallback(words.NewLexicon(model.WordTagFreqs(), model.UnigramFreqs()), sh, substitutions)
+	model, handlers := common.MustLoadModelWithHandlers(config.Model)
+	if err := config.UsePrecomputedHandlers(handlers); err != nil {
+		fmt.Fprintf(os.Stderr, "Not using precomputed handlers, constructing them instead: %s\n", err)
 	}
+
+	lh, err := config.WordHandler(model, substitutions)
+	common.ExitIfError("Could not construct word handler", err)
 	lim := trigrams.NewLinearInterpolationModel(model)
 	tagger := tagger.NewHMMTagger(model, lh, lim, 1000.0)
 
+	// Lemmas are only assigned when the training data contained lemmas.
+	var lemmatizer *lemma.Lemmatizer
+	if len(model.LemmaFreqs()) != 0 {
+		l := lemma.NewLemmatizer(config.Lemmatizer, model)
+		lemmatizer = &l
+	}
+
 	reader := conllx.NewReader(bufio.NewReader(inputFile))
 	bufWriter := bufio.NewWriter(outputFile)
 	defer bufWriter.Flush()
@@ -90,6 +88,16 @@ func main() {
 
 		words := tokenToWords(sent)
 		tags, _ := tagger.Tag(words).Tags()
+		if lemmatizer != nil {
+			addLemmas(lemmatizer, sent, words, tags)
+		}
+		if len(model.Features()) != 0 {
+			addFeatures(sent, tags, model.Features())
+		}
+		if tagMapping != nil {
+			err = mapTags(tagMapping, tags)
+			common.ExitIfError("Cannot map tags", err)
+		}
 		addTags(sent, tags)
 
 		err = writer.WriteSentence(sent)
@@ -118,3 +126,54 @@ func addTags(sent []conllx.Token, tags []string) {
 		sent[i].SetPosTag(tags[i])
 	}
 }
+
+// addLemmas assigns lemmas to the tokens of a sentence. The lemmas are
+// predicted using the tags of the model, before tag mapping is applied.
+func addLemmas(lemmatizer *lemma.Lemmatizer, sent []conllx.Token, words, tags []string) {
+	for i := range sent {
+		sent[i].SetLemma(lemmatizer.Lemma(words[i], tags[i]))
+	}
+}
+
+// addFeatures writes the features of composite tag labels to the
+// features column and replaces the labels by their part-of-speech tags.
+// Features of the input that are not predicted by the tagger are retained.
+func addFeatures(sent []conllx.Token, tags []string, names []string) {
+	for i, tag := range tags {
+		pos, featuresString := model.SplitLabel(tag)
+		tags[i] = pos
+
+		features := make(map[string]string)
+		if f, ok := sent[i].Features(); ok {
+			for name, value := range f.FeaturesMap() {
+				features[name] = value
+			}
+		}
+
+		for _, name := range names {
+			delete(features, name)
+		}
+
+		for _, feature := range strings.Split(featuresString, model.FeatureSeparator) {
+			if sepIdx := strings.IndexByte(feature, ':'); sepIdx != -1 {
+				features[feature[:sepIdx]] = feature[sepIdx+1:]
+			}
+		}
+
+		if len(features) != 0 {
+			sent[i].SetFeatures(features)
+		}
+	}
+}
+
+func mapTags(tagMapping model.TagMapping, tags []string) error {
+	for i, tag := range tags {
+		mapped, ok := tagMapping.Map(tag)
+		if !ok {
+			return fmt.Errorf("no mapping for tag: %s", tag)
+		}
+		tags[i] = mapped
+	}
+
+	return nil
+}
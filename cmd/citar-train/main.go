@@ -13,7 +13,6 @@ import (
 	"os"
 
 	"github.com/danieldk/citar/cmd/common"
-	"github.com/danieldk/citar/model"
 	"github.com/danieldk/conllx"
 )
 
@@ -24,7 +23,8 @@ func init() {
 	}
 }
 
-var closedClassFilename = flag.String("closed-class", "", "file with closed-class tags")
+var closedClassFilename = flag.String("closed-class", "", "file with closed-class tags, in addition to the closed_class section")
+var tagMappingFilename = flag.String("tag-mapping", "", "file with a mapping of fine to coarse tags, to train on coarse tags")
 
 func main() {
 	flag.Parse()
@@ -37,6 +37,7 @@ func main() {
 	config := common.MustParseConfig(flag.Arg(0))
 
 	closedClass := common.MustLoadClosedClass(*closedClassFilename)
+	tagMapping := common.MustLoadTagMapping(*tagMappingFilename)
 
 	f, err := os.Open(flag.Arg(1))
 	common.ExitIfError("Cannot open training data", err)
@@ -51,7 +52,7 @@ func main() {
 
 	reader := conllx.NewReader(bufio.NewReader(f))
 
-	fc := model.NewFrequencyCollector()
+	fc := config.FrequencyCollector(tagMapping)
 
 	for {
 		sent, err := reader.ReadSentence()
@@ -65,8 +66,14 @@ func main() {
 		common.ExitIfError("Cannot process sentence", err)
 	}
 
-	model := fc.ModelWithClosedClass(closedClass)
+	model, err := config.TrainedModel(fc, closedClass)
+	common.ExitIfError("Cannot determine closed-class tags", err)
 	enc := gob.NewEncoder(bufOut)
 	err = enc.Encode(model)
 	common.ExitIfError("Cannot encode model", err)
+
+	// Store the suffix handlers, so that they do not have to be
+	// constructed when the model is loaded.
+	err = enc.Encode(config.PrecomputeHandlers(model))
+	common.ExitIfError("Cannot encode precomputed handlers", err)
 }
@@ -9,34 +9,402 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/BurntSushi/toml"
+	"github.com/danieldk/citar/lemma"
 	"github.com/danieldk/citar/model"
 	"github.com/danieldk/citar/words"
 )
 
-// CitarConfig stores the configuration of citar.
+// CitarConfig stores the configuration of citar. Parameters that are not
+// specified retain their default values.
+//
+// The parameters of the word handlers are read from their own sections:
+// [suffix_handler], [prefix_handler], [maxent_handler], [compound_handler],
+// [cluster_handler], [char_ngram_handler], [interpolation],
+// [external_lexicon], and [lexicon_smoothing]. Known words are smoothed
+// using the unknown word handler.
+//
+// If HyphenLookup is enabled, the last component of hyphenated unknown
+// words is looked up in the lexicon before the unknown word handler is
+// used. If SubstUnknown is enabled, the substitutions are also applied to
+// words before they are passed to the unknown word handler.
+//
+// The word normalization of the [normalization] section, the closed-class
+// tags of the [closed_class] section, Capitalization, and Features are
+// applied during training and stored in the model. During tagging, the
+// values that are stored in the model are used. Capitalization determines
+// how capitalization is modelled in the tag set. Features are the
+// morphological features that are combined with the part-of-speech tag
+// into the tag label.
+//
+// If the model contains lemmas, the lemmatizer of the [lemmatizer]
+// section assigns lemmas to the tagged words.
 type CitarConfig struct {
-	Model          string
-	Substitutions  string
-	UnknownHandler string `toml:"unknown_handler"`
+	Model            string
+	Substitutions    string
+	Clusters         string
+	SubstUnknown     bool                         `toml:"substitute_unknown"`
+	UnknownHandler   string                       `toml:"unknown_handler"`
+	SuffixHandler    words.SuffixHandlerConfig    `toml:"suffix_handler"`
+	PrefixHandler    words.PrefixHandlerConfig    `toml:"prefix_handler"`
+	PrefixWeight     float64                      `toml:"prefix_weight"`
+	MaxEntHandler    words.MaxEntHandlerConfig    `toml:"maxent_handler"`
+	CompoundHandler  words.CompoundHandlerConfig  `toml:"compound_handler"`
+	ClusterHandler   words.ClusterHandlerConfig   `toml:"cluster_handler"`
+	CharNGramHandler words.CharNGramHandlerConfig `toml:"char_ngram_handler"`
+	Interpolation    InterpolationConfig          `toml:"interpolation"`
+	HyphenLookup     bool                         `toml:"hyphen_lookup"`
+	ExternalLexicon  ExternalLexiconConfig        `toml:"external_lexicon"`
+	LexiconSmoothing words.LexiconSmoothingConfig `toml:"lexicon_smoothing"`
+	Normalization    model.Normalization          `toml:"normalization"`
+	ClosedClass      ClosedClassConfig            `toml:"closed_class"`
+	Capitalization   model.Capitalization         `toml:"capitalization"`
+	Features         []string                     `toml:"features"`
+	Lemmatizer       lemma.LemmatizerConfig       `toml:"lemmatizer"`
+
+	precomputed PrecomputedHandlers
+}
+
+// ExternalLexiconConfig stores the configuration of an external full-form
+// lexicon. When File is set, words that are not in the training lexicon
+// are looked up in the external lexicon before the unknown word handler
+// is used. Mode is "restrict" to only use the tags of the external
+// lexicon or "augment" to interpolate with the unknown word handler,
+// giving the external lexicon the weight Weight. An external lexicon
+// cannot be used with a model that has features.
+type ExternalLexiconConfig struct {
+	File   string  `toml:"file"`
+	Mode   string  `toml:"mode"`
+	Weight float64 `toml:"weight"`
+}
+
+// ClosedClassConfig stores the configuration of the closed-class tags,
+// which are never assigned to unknown words. The tags are read from File
+// (one tag per line) and Tags. If Infer is enabled, tags that were
+// assigned to at most InferMaxTypes word types and at least InferMinFreq
+// tokens in the training data are also closed-class tags.
+type ClosedClassConfig struct {
+	File          string   `toml:"file"`
+	Tags          []string `toml:"tags"`
+	Infer         bool     `toml:"infer"`
+	InferMaxTypes int      `toml:"infer_max_types"`
+	InferMinFreq  int      `toml:"infer_min_freq"`
+}
+
+// Validate checks that the configuration is valid.
+func (c ClosedClassConfig) Validate() error {
+	if c.InferMaxTypes < 1 {
+		return fmt.Errorf("maximum number of word types should be at least 1: %d", c.InferMaxTypes)
+	}
+
+	if c.InferMinFreq < 0 {
+		return fmt.Errorf("minimum frequency should not be negative: %d", c.InferMinFreq)
+	}
+
+	return nil
+}
+
+// ClosedClassTags returns the closed-class tags for a model that is
+// trained on the training data. The closed-class tags are the union of
+// the given tags (e.g. from a file that is specified on the command line),
+// the tags of the closed_class section, and the inferred tags if inference
+// is enabled.
+func (c CitarConfig) ClosedClassTags(m model.Model, tags model.ClosedClassSet) (model.ClosedClassSet, error) {
+	closedClass := make(model.ClosedClassSet)
+	for tag := range tags {
+		closedClass[tag] = nil
+	}
+
+	for _, tag := range c.ClosedClass.Tags {
+		closedClass[tag] = nil
+	}
+
+	if c.ClosedClass.File != "" {
+		f, err := os.Open(c.ClosedClass.File)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		fileTags, err := readClosedClass(f)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read closed-class tags %s: %s", c.ClosedClass.File, err)
+		}
+
+		for tag := range fileTags {
+			closedClass[tag] = nil
+		}
+	}
+
+	if c.ClosedClass.Infer {
+		for tag := range model.InferClosedClassTags(m, c.ClosedClass.InferMaxTypes, c.ClosedClass.InferMinFreq) {
+			closedClass[tag] = nil
+		}
+	}
+
+	return closedClass, nil
+}
+
+// FrequencyCollector returns a frequency collector for the training data
+// that uses the normalization, capitalization, and features of the
+// configuration. The tag mapping is optional and can be nil.
+func (c CitarConfig) FrequencyCollector(tagMapping model.TagMapping) model.FrequencyCollector {
+	return model.NewFrequencyCollector(model.FrequencyCollectorOptions{
+		TagMapping:     tagMapping,
+		Normalization:  c.Normalization,
+		Capitalization: c.Capitalization,
+		Features:       c.Features,
+	})
+}
+
+// TrainedModel returns the model of the frequencies that were collected
+// from the training data, with the closed-class tags of ClosedClassTags.
+func (c CitarConfig) TrainedModel(fc model.FrequencyCollector, tags model.ClosedClassSet) (model.Model, error) {
+	m := fc.Model()
+
+	closedClass, err := c.ClosedClassTags(m, tags)
+	if err != nil {
+		return m, err
+	}
+
+	return m.WithClosedClassTags(closedClass), nil
+}
+
+// InterpolationConfig stores the configuration of the "interpolated"
+// unknown word handler, which interpolates the distributions of several
+// handlers. Mode is "linear" or "log_linear". Each handler is one of the
+// other unknown word handlers or "external_lexicon", which only provides
+// tags for words that are in the external lexicon.
+type InterpolationConfig struct {
+	Mode     string                      `toml:"mode"`
+	Handlers []InterpolatedHandlerConfig `toml:"handler"`
+}
+
+// InterpolatedHandlerConfig stores the configuration of a handler of
+// the "interpolated" unknown word handler. ClassWeights maps word classes
+// of the [suffix_handler] section to weights that are used instead of
+// Weight for words in these classes.
+type InterpolatedHandlerConfig struct {
+	Name         string             `toml:"name"`
+	Weight       float64            `toml:"weight"`
+	ClassWeights map[string]float64 `toml:"class_weights"`
+}
+
+func (c CitarConfig) validateInterpolation() error {
+	if _, err := words.ParseInterpolationMode(c.Interpolation.Mode); err != nil {
+		return err
+	}
+
+	classifier, err := words.NewWordClassifier(c.SuffixHandler)
+	if err != nil {
+		return err
+	}
+
+	classes := make(map[string]interface{})
+	for _, class := range classifier.Classes() {
+		classes[class] = nil
+	}
+
+	var weightSum float64
+	for _, handler := range c.Interpolation.Handlers {
+		if _, ok := unknownHandlers[handler.Name]; !ok && handler.Name != externalLexiconHandler {
+			return fmt.Errorf("unknown handler: %s", handler.Name)
+		}
+
+		if handler.Name == "interpolated" {
+			return fmt.Errorf("the interpolated handler cannot be nested")
+		}
+
+		if handler.Weight < 0 {
+			return fmt.Errorf("weight of %s should not be negative: %f", handler.Name, handler.Weight)
+		}
+		weightSum += handler.Weight
+
+		for class, weight := range handler.ClassWeights {
+			if _, ok := classes[class]; !ok {
+				return fmt.Errorf("unknown word class for %s: %s", handler.Name, class)
+			}
+
+			if weight < 0 {
+				return fmt.Errorf("weight of %s for class %s should not be negative: %f",
+					handler.Name, class, weight)
+			}
+		}
+	}
+
+	if len(c.Interpolation.Handlers) != 0 && weightSum == 0 {
+		return fmt.Errorf("at least one handler should have a positive weight")
+	}
+
+	return nil
 }
 
+// externalLexiconHandler is the name of the external lexicon when it is
+// used as a handler of the interpolated handler.
+const externalLexiconHandler = "external_lexicon"
+
 // UnknownWordHandler returns a word handler given the tagger
 // configuration and a data model.
 func (c CitarConfig) UnknownWordHandler(m model.Model) (words.WordHandler, error) {
-	if cons, ok := unknownHandlers[c.UnknownHandler]; ok {
-		return cons(m), nil
+	cons, ok := unknownHandlers[c.UnknownHandler]
+	if !ok {
+		return nil, fmt.Errorf("Unknown word handler: %s", c.UnknownHandler)
+	}
+
+	handler, err := cons(c, m)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.HyphenLookup {
+		handler = words.NewHyphenHandler(m, handler)
+	}
+
+	// Do not use the external lexicon twice when it is interpolated.
+	if c.ExternalLexicon.File != "" && !c.usesHandler(externalLexiconHandler) {
+		handler, err = c.externalLexiconHandler(m, handler)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Unknown words should never receive closed-class tags.
+	return words.NewOpenClassHandler(handler, m), nil
+}
+
+func (c CitarConfig) externalLexiconHandler(m model.Model, fallback words.WordHandler) (words.WordHandler, error) {
+	if c.ExternalLexicon.File == "" {
+		return nil, fmt.Errorf("the external lexicon handler requires an external lexicon file")
+	}
+
+	// The tags of the model are composite labels, which do not match the
+	// part-of-speech tags of an external lexicon.
+	if len(m.Features()) != 0 {
+		return nil, fmt.Errorf("an external lexicon cannot be used with a model that has features")
+	}
+
+	mode, err := words.ParseExternalLexiconMode(c.ExternalLexicon.Mode)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(c.ExternalLexicon.File)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lexicon, err := words.ReadExternalLexicon(f)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read external lexicon %s: %s", c.ExternalLexicon.File, err)
 	}
 
-	return nil, fmt.Errorf("Unknown word handler: %s", c.UnknownHandler)
+	return words.NewExternalLexiconHandler(lexicon, m, fallback, mode,
+		c.ExternalLexicon.Weight), nil
+}
+
+func interpolatedHandler(c CitarConfig, m model.Model) (words.WordHandler, error) {
+	if len(c.Interpolation.Handlers) == 0 {
+		return nil, fmt.Errorf("the interpolated handler requires at least one handler")
+	}
+
+	mode, err := words.ParseInterpolationMode(c.Interpolation.Mode)
+	if err != nil {
+		return nil, err
+	}
+
+	var classifier *words.WordClassifier
+	var components []words.InterpolatedComponent
+	for _, config := range c.Interpolation.Handlers {
+		var handler words.WordHandler
+		if config.Name == externalLexiconHandler {
+			handler, err = c.externalLexiconHandler(m, nil)
+		} else {
+			cons, ok := unknownHandlers[config.Name]
+			if !ok || config.Name == "interpolated" {
+				return nil, fmt.Errorf("Unknown word handler: %s", config.Name)
+			}
+
+			handler, err = cons(c, m)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(config.ClassWeights) != 0 && classifier == nil {
+			classifier, err = words.NewWordClassifier(c.SuffixHandler)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		components = append(components, words.InterpolatedComponent{
+			Handler:      handler,
+			Weight:       config.Weight,
+			ClassWeights: config.ClassWeights,
+		})
+	}
+
+	return words.NewInterpolatedHandler(components, classifier, mode), nil
+}
+
+// WordHandler returns the word handler for known and unknown words given
+// the tagger configuration, a data model, and substitution rules.
+func (c CitarConfig) WordHandler(m model.Model, substitutions []words.Substitution) (words.WordHandler, error) {
+	sh, err := c.UnknownWordHandler(m)
+	if err != nil {
+		return nil, err
+	}
+
+	var lh words.WordHandler
+	if len(substitutions) == 0 {
+		lh = words.NewSmoothedLexicon(m.WordTagFreqs(), m.UnigramFreqs(), sh, sh,
+			c.LexiconSmoothing)
+	} else {
+		if c.SubstUnknown {
+			sh = words.NewSubstHandler(sh, substitutions)
+		}
+
+		lexicon := words.NewSmoothedLexicon(m.WordTagFreqs(), m.UnigramFreqs(), nil, sh,
+			c.LexiconSmoothing)
+		lh = words.NewSubstLexiconWithFallback(lexicon, sh, substitutions)
+	}
+
+	if !m.Normalization().IsIdentity() {
+		lh = words.NewNormalizingHandler(lh, m)
+	}
+
+	return lh, nil
 }
 
 func defaultConfiguration() *CitarConfig {
 	return &CitarConfig{
-		Model:          "model.gob",
-		Substitutions:  "",
-		UnknownHandler: "lookup",
+		Model:            "model.gob",
+		Substitutions:    "",
+		UnknownHandler:   "lookup",
+		SuffixHandler:    words.DefaultSuffixHandlerConfig(),
+		PrefixHandler:    words.DefaultPrefixHandlerConfig(),
+		PrefixWeight:     0.3,
+		MaxEntHandler:    words.DefaultMaxEntHandlerConfig(),
+		CompoundHandler:  words.DefaultCompoundHandlerConfig(),
+		ClusterHandler:   words.DefaultClusterHandlerConfig(),
+		CharNGramHandler: words.DefaultCharNGramHandlerConfig(),
+		Interpolation: InterpolationConfig{
+			Mode: "linear",
+		},
+		ExternalLexicon: ExternalLexiconConfig{
+			Mode:   "restrict",
+			Weight: 0.5,
+		},
+		LexiconSmoothing: words.DefaultLexiconSmoothingConfig(),
+		Capitalization:   model.CapitalizationBit,
+		Lemmatizer:       lemma.DefaultLemmatizerConfig(),
+		ClosedClass: ClosedClassConfig{
+			InferMaxTypes: 50,
+			InferMinFreq:  100,
+		},
 	}
 }
 
@@ -52,32 +420,162 @@ func MustParseConfig(filename string) *CitarConfig {
 
 	config.Model = relToConfig(filename, config.Model)
 	config.Substitutions = relToConfig(filename, config.Substitutions)
+	config.Clusters = relToConfig(filename, config.Clusters)
+	config.ExternalLexicon.File = relToConfig(filename, config.ExternalLexicon.File)
+	config.ClosedClass.File = relToConfig(filename, config.ClosedClass.File)
 
 	return config
 }
 
 // ParseConfig attempts to parse the configuration from the given reader.
+// The configuration is validated after parsing.
 func ParseConfig(reader io.Reader) (*CitarConfig, error) {
 	config := defaultConfiguration()
 	if _, err := toml.DecodeReader(reader, config); err != nil {
 		return config, err
 	}
 
+	if err := config.SuffixHandler.Validate(); err != nil {
+		return config, fmt.Errorf("invalid suffix_handler section: %s", err)
+	}
+
+	if err := config.PrefixHandler.Validate(); err != nil {
+		return config, fmt.Errorf("invalid prefix_handler section: %s", err)
+	}
+
+	if config.PrefixWeight < 0 || config.PrefixWeight > 1 {
+		return config, fmt.Errorf("prefix_weight should be in [0, 1]: %f", config.PrefixWeight)
+	}
+
+	if err := config.MaxEntHandler.Validate(); err != nil {
+		return config, fmt.Errorf("invalid maxent_handler section: %s", err)
+	}
+
+	if err := config.CompoundHandler.Validate(); err != nil {
+		return config, fmt.Errorf("invalid compound_handler section: %s", err)
+	}
+
+	if err := config.ClusterHandler.Validate(); err != nil {
+		return config, fmt.Errorf("invalid cluster_handler section: %s", err)
+	}
+
+	if err := config.CharNGramHandler.Validate(); err != nil {
+		return config, fmt.Errorf("invalid char_ngram_handler section: %s", err)
+	}
+
+	if err := config.validateInterpolation(); err != nil {
+		return config, fmt.Errorf("invalid interpolation section: %s", err)
+	}
+
+	if err := config.Normalization.Validate(); err != nil {
+		return config, fmt.Errorf("invalid normalization section: %s", err)
+	}
+
+	if err := config.Capitalization.Validate(); err != nil {
+		return config, err
+	}
+
+	if err := config.ClosedClass.Validate(); err != nil {
+		return config, fmt.Errorf("invalid closed_class section: %s", err)
+	}
+
+	if err := validateFeatures(config.Features); err != nil {
+		return config, err
+	}
+
+	if err := config.Lemmatizer.Validate(); err != nil {
+		return config, fmt.Errorf("invalid lemmatizer section: %s", err)
+	}
+
+	if err := config.LexiconSmoothing.Validate(); err != nil {
+		return config, fmt.Errorf("invalid lexicon_smoothing section: %s", err)
+	}
+
+	if _, err := words.ParseExternalLexiconMode(config.ExternalLexicon.Mode); err != nil {
+		return config, fmt.Errorf("invalid external_lexicon section: %s", err)
+	}
+
+	if config.ExternalLexicon.Weight < 0 || config.ExternalLexicon.Weight > 1 {
+		return config, fmt.Errorf("invalid external_lexicon section: weight should be in [0, 1]: %f",
+			config.ExternalLexicon.Weight)
+	}
+
 	return config, nil
 }
 
-type unknownHandler func(m model.Model) words.WordHandler
+// validateFeatures checks that the feature names can be used in
+// composite tag labels.
+func validateFeatures(features []string) error {
+	seen := make(map[string]interface{})
+	for _, name := range features {
+		if name == "" || strings.ContainsAny(name, model.FeatureSeparator+":") {
+			return fmt.Errorf("invalid feature name: %q", name)
+		}
+
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("duplicate feature name: %s", name)
+		}
+		seen[name] = nil
+	}
+
+	return nil
+}
+
+type unknownHandler func(c CitarConfig, m model.Model) (words.WordHandler, error)
 
 // UnknownHandlers is a mapping from unknown words handlers to
 // constructors of these handlers.
 var unknownHandlers = map[string]unknownHandler{
-	"tree": func(m model.Model) words.WordHandler {
-		return words.NewSuffixHandler(words.DefaultSuffixHandlerConfig(), m)
+	"tree": func(c CitarConfig, m model.Model) (words.WordHandler, error) {
+		return c.suffixHandler(m), nil
+	},
+	"lookup": func(c CitarConfig, m model.Model) (words.WordHandler, error) {
+		return c.lookupSuffixHandler(m), nil
+	},
+	"prefix": func(c CitarConfig, m model.Model) (words.WordHandler, error) {
+		return words.NewPrefixHandler(c.PrefixHandler, m), nil
+	},
+	"prefix_suffix": func(c CitarConfig, m model.Model) (words.WordHandler, error) {
+		return words.NewPrefixSuffixHandler(
+			words.NewPrefixHandler(c.PrefixHandler, m),
+			c.lookupSuffixHandler(m),
+			c.PrefixWeight), nil
 	},
-	"lookup": func(m model.Model) words.WordHandler {
-		return words.NewLookupSuffixHandler(
-			words.NewSuffixHandler(words.DefaultSuffixHandlerConfig(), m))
+	"maxent": func(c CitarConfig, m model.Model) (words.WordHandler, error) {
+		return words.NewMaxEntHandler(c.MaxEntHandler, m), nil
 	},
+	"compound": func(c CitarConfig, m model.Model) (words.WordHandler, error) {
+		return words.NewCompoundHandler(c.CompoundHandler, m,
+			c.lookupSuffixHandler(m)), nil
+	},
+	"char_ngram": func(c CitarConfig, m model.Model) (words.WordHandler, error) {
+		return words.NewCharNGramHandler(c.CharNGramHandler, m), nil
+	},
+	"cluster": func(c CitarConfig, m model.Model) (words.WordHandler, error) {
+		if c.Clusters == "" {
+			return nil, fmt.Errorf("the cluster handler requires a clusters file")
+		}
+
+		f, err := os.Open(c.Clusters)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		clusters, err := words.ReadWordClusters(f)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read word clusters %s: %s", c.Clusters, err)
+		}
+
+		return words.NewClusterHandler(c.ClusterHandler, clusters, m,
+			c.lookupSuffixHandler(m)), nil
+	},
+}
+
+func init() {
+	// Registered here, since the interpolated handler constructs the
+	// other handlers.
+	unknownHandlers["interpolated"] = interpolatedHandler
 }
 
 // Return the path of a file, relative to the directory of
@@ -13,14 +13,26 @@ import (
 )
 
 // The Evaluator type is used to keep counts on the number of
-// correctly/incorrectly tagged known/unknown tokens.
+// correctly/incorrectly tagged known/unknown tokens. If the model has
+// features, tokens are counted as correct when both the part-of-speech
+// tag and the features are correct. The part-of-speech tags are also
+// evaluated separately.
 type Evaluator struct {
-	tagger           tagger.HMMTagger
-	model            model.Model
-	knownCorrect     uint
-	knownIncorrect   uint
-	unknownCorrect   uint
-	unknownIncorrect uint
+	tagger                 tagger.HMMTagger
+	model                  model.Model
+	tagMapping             model.TagMapping
+	knownCorrect           uint
+	knownIncorrect         uint
+	unknownCorrect         uint
+	unknownIncorrect       uint
+	coarseKnownCorrect     uint
+	coarseKnownIncorrect   uint
+	coarseUnknownCorrect   uint
+	coarseUnknownIncorrect uint
+	posKnownCorrect        uint
+	posKnownIncorrect      uint
+	posUnknownCorrect      uint
+	posUnknownIncorrect    uint
 }
 
 // NewEvaluator creates an evaluator that uses the provided tagger and
@@ -33,6 +45,19 @@ func NewEvaluator(tagger tagger.HMMTagger, model model.Model) *Evaluator {
 	}
 }
 
+// NewEvaluatorWithTagMapping creates an evaluator that uses the provided
+// tagger and the corresponding model. Besides counting the correctly
+// tagged tokens, it also counts tokens that are tagged correctly after
+// mapping the tags with the given tag mapping.
+func NewEvaluatorWithTagMapping(tagger tagger.HMMTagger, model model.Model,
+	tagMapping model.TagMapping) *Evaluator {
+	return &Evaluator{
+		tagger:     tagger,
+		model:      model,
+		tagMapping: tagMapping,
+	}
+}
+
 // Process a sentence, tagging it using the Evaluator's tagger and counting
 // the number of tokens that were tagged correctly.
 func (e *Evaluator) Process(sent []conllx.Token) error {
@@ -48,9 +73,9 @@ func (e *Evaluator) Process(sent []conllx.Token) error {
 	tags, _ := e.tagger.Tag(words).Tags()
 
 	for idx, token := range sent {
-		_, inLexicon := e.model.WordTagFreqs()[words[idx]]
+		_, inLexicon := e.model.WordTagFreqs()[e.model.Normalization().Normalize(words[idx])]
 
-		correctTag, ok := token.PosTag()
+		correctTag, ok := e.model.TokenLabel(token)
 		if !ok {
 			return fmt.Errorf("Token does not have a tag: %s", token)
 		}
@@ -69,6 +94,63 @@ func (e *Evaluator) Process(sent []conllx.Token) error {
 			}
 		}
 
+		// Tag mappings and POS accuracy apply to the part-of-speech tags.
+		pos, _ := model.SplitLabel(tags[idx])
+		correctPos, _ := model.SplitLabel(correctTag)
+
+		if len(e.model.Features()) != 0 {
+			e.processPOS(pos, correctPos, inLexicon)
+		}
+
+		if e.tagMapping != nil {
+			if err := e.processCoarse(pos, correctPos, inLexicon); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (e *Evaluator) processPOS(pos, correctPos string, inLexicon bool) {
+	if pos == correctPos {
+		if inLexicon {
+			e.posKnownCorrect++
+		} else {
+			e.posUnknownCorrect++
+		}
+	} else {
+		if inLexicon {
+			e.posKnownIncorrect++
+		} else {
+			e.posUnknownIncorrect++
+		}
+	}
+}
+
+func (e *Evaluator) processCoarse(tag, correctTag string, inLexicon bool) error {
+	coarseTag, ok := e.tagMapping.Map(tag)
+	if !ok {
+		return fmt.Errorf("No mapping for tag: %s", tag)
+	}
+
+	coarseCorrectTag, ok := e.tagMapping.Map(correctTag)
+	if !ok {
+		return fmt.Errorf("No mapping for tag: %s", correctTag)
+	}
+
+	if coarseTag == coarseCorrectTag {
+		if inLexicon {
+			e.coarseKnownCorrect++
+		} else {
+			e.coarseUnknownCorrect++
+		}
+	} else {
+		if inLexicon {
+			e.coarseKnownIncorrect++
+		} else {
+			e.coarseUnknownIncorrect++
+		}
 	}
 
 	return nil
@@ -118,3 +200,85 @@ func (e *Evaluator) Accuracy() float64 {
 func (e *Evaluator) UnknownAccuracy() float64 {
 	return float64(e.UnknownCorrect()) / float64(e.UnknownCorrect()+e.UnknownIncorrect())
 }
+
+// CoarseKnownCorrect returns the number of known words that are tagged
+// correctly after tag mapping.
+func (e *Evaluator) CoarseKnownCorrect() uint {
+	return e.coarseKnownCorrect
+}
+
+// CoarseKnownIncorrect returns the number of known words that are tagged
+// incorrectly after tag mapping.
+func (e *Evaluator) CoarseKnownIncorrect() uint {
+	return e.coarseKnownIncorrect
+}
+
+// CoarseUnknownCorrect returns the number of unknown words that are tagged
+// correctly after tag mapping.
+func (e *Evaluator) CoarseUnknownCorrect() uint {
+	return e.coarseUnknownCorrect
+}
+
+// CoarseUnknownIncorrect returns the number of unknown words that are
+// tagged incorrectly after tag mapping.
+func (e *Evaluator) CoarseUnknownIncorrect() uint {
+	return e.coarseUnknownIncorrect
+}
+
+// CoarseKnownAccuracy returns the tagging accuracy of known words after
+// tag mapping.
+func (e *Evaluator) CoarseKnownAccuracy() float64 {
+	return float64(e.coarseKnownCorrect) / float64(e.coarseKnownCorrect+e.coarseKnownIncorrect)
+}
+
+// CoarseAccuracy returns the tagging accuracy after tag mapping.
+func (e *Evaluator) CoarseAccuracy() float64 {
+	correct := e.coarseKnownCorrect + e.coarseUnknownCorrect
+	return float64(correct) / float64(correct+e.coarseKnownIncorrect+e.coarseUnknownIncorrect)
+}
+
+// CoarseUnknownAccuracy returns the tagging accuracy of unknown words after
+// tag mapping.
+func (e *Evaluator) CoarseUnknownAccuracy() float64 {
+	return float64(e.coarseUnknownCorrect) / float64(e.coarseUnknownCorrect+e.coarseUnknownIncorrect)
+}
+
+// POSKnownCorrect returns the number of known words of which the
+// part-of-speech tag is correct. Only counted if the model has features.
+func (e *Evaluator) POSKnownCorrect() uint {
+	return e.posKnownCorrect
+}
+
+// POSKnownIncorrect returns the number of known words of which the
+// part-of-speech tag is incorrect. Only counted if the model has features.
+func (e *Evaluator) POSKnownIncorrect() uint {
+	return e.posKnownIncorrect
+}
+
+// POSUnknownCorrect returns the number of unknown words of which the
+// part-of-speech tag is correct. Only counted if the model has features.
+func (e *Evaluator) POSUnknownCorrect() uint {
+	return e.posUnknownCorrect
+}
+
+// POSUnknownIncorrect returns the number of unknown words of which the
+// part-of-speech tag is incorrect. Only counted if the model has features.
+func (e *Evaluator) POSUnknownIncorrect() uint {
+	return e.posUnknownIncorrect
+}
+
+// POSKnownAccuracy returns the part-of-speech accuracy of known words.
+func (e *Evaluator) POSKnownAccuracy() float64 {
+	return float64(e.posKnownCorrect) / float64(e.posKnownCorrect+e.posKnownIncorrect)
+}
+
+// POSAccuracy returns the part-of-speech accuracy.
+func (e *Evaluator) POSAccuracy() float64 {
+	correct := e.posKnownCorrect + e.posUnknownCorrect
+	return float64(correct) / float64(correct+e.posKnownIncorrect+e.posUnknownIncorrect)
+}
+
+// POSUnknownAccuracy returns the part-of-speech accuracy of unknown words.
+func (e *Evaluator) POSUnknownAccuracy() float64 {
+	return float64(e.posUnknownCorrect) / float64(e.posUnknownCorrect+e.posUnknownIncorrect)
+}
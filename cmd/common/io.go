@@ -2,68 +2,117 @@ package common
 
 import (
 	"bufio"
+	"encoding/gob"
 	"fmt"
+	"io"
 	"os"
-	"regexp"
 	"strings"
 
 	"github.com/danieldk/citar/model"
 	"github.com/danieldk/citar/words"
 )
 
+// MustLoadClosedClass loads closed-class tags from the given file. If the
+// filename is empty, an empty set is returned.
 func MustLoadClosedClass(filename string) model.ClosedClassSet {
-	tags := make(model.ClosedClassSet)
-
 	if filename == "" {
-		return tags
+		return make(model.ClosedClassSet)
 	}
 
 	f, err := os.Open(filename)
 	ExitIfError("cannot open closed class tag file", err)
 	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
+	tags, err := readClosedClass(f)
+	ExitIfError("cannot read closed class tag file", err)
+
+	return tags
+}
 
+// readClosedClass reads closed-class tags, one tag per line.
+func readClosedClass(reader io.Reader) (model.ClosedClassSet, error) {
+	tags := make(model.ClosedClassSet)
+
+	scanner := bufio.NewScanner(reader)
 	for scanner.Scan() {
-		line := scanner.Text()
-		tag := strings.TrimSpace(line)
+		tag := strings.TrimSpace(scanner.Text())
 
 		if tag != "" {
 			tags[tag] = nil
 		}
 	}
 
-	return tags
+	return tags, scanner.Err()
 }
 
-func MustLoadSubstitutions(filename string) []words.Substitution {
-	substs := make([]words.Substitution, 0)
+// MustLoadTagMapping loads a tag mapping from the given file. If the
+// filename is empty, nil is returned.
+func MustLoadTagMapping(filename string) model.TagMapping {
+	if filename == "" {
+		return nil
+	}
+
+	f, err := os.Open(filename)
+	ExitIfError("cannot open tag mapping file", err)
+	defer f.Close()
+
+	mapping, err := model.ReadTagMapping(f)
+	ExitIfError("cannot read tag mapping", err)
+
+	return mapping
+}
 
+// MustLoadSubstitutions loads substitution rules from the given file. If
+// the filename is empty, no substitutions are returned.
+func MustLoadSubstitutions(filename string) []words.Substitution {
 	if filename == "" {
-		return substs
+		return nil
 	}
 
 	f, err := os.Open(filename)
 	ExitIfError("cannot open substitution file", err)
 	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
+	substs, err := words.ReadSubstitutions(f)
+	ExitIfError(fmt.Sprintf("cannot read substitution file %s", filename), err)
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		line = strings.TrimSpace(line)
-
-		if line != "" {
-			parts := strings.Split(line, "\t")
-			if len(parts) != 2 {
-				fmt.Fprintf(os.Stderr, "Incorrect substitution: %s\n", line)
-				os.Exit(1)
-			}
-
-			pattern := regexp.MustCompile(parts[0])
-			substs = append(substs, words.Substitution{Pattern: pattern, Replacement: parts[1]})
-		}
+	return substs
+}
+
+// MustLoadModel loads a gob-encoded model from the given file. If the
+// model cannot be loaded, the process is exited with an error message.
+func MustLoadModel(filename string) model.Model {
+	f, err := os.Open(filename)
+	ExitIfError("Cannot open model", err)
+	defer f.Close()
+
+	var m model.Model
+	decoder := gob.NewDecoder(bufio.NewReader(f))
+	err = decoder.Decode(&m)
+	ExitIfError("Could not load model", err)
+
+	return m
+}
+
+// MustLoadModelWithHandlers loads a gob-encoded model and the handlers
+// that were precomputed during training from the given file. Models
+// without precomputed handlers are also accepted. If the model cannot be
+// loaded, the process is exited with an error message.
+func MustLoadModelWithHandlers(filename string) (model.Model, PrecomputedHandlers) {
+	f, err := os.Open(filename)
+	ExitIfError("Cannot open model", err)
+	defer f.Close()
+
+	var m model.Model
+	decoder := gob.NewDecoder(bufio.NewReader(f))
+	err = decoder.Decode(&m)
+	ExitIfError("Could not load model", err)
+
+	var handlers PrecomputedHandlers
+	err = decoder.Decode(&handlers)
+	if err != io.EOF {
+		ExitIfError("Could not load precomputed handlers", err)
 	}
 
-	return substs
+	return m, handlers
 }
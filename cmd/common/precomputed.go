@@ -0,0 +1,119 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package common
+
+import (
+	"fmt"
+
+	"github.com/danieldk/citar/model"
+	"github.com/danieldk/citar/words"
+)
+
+// PrecomputedHandlers stores unknown word handlers that are computed
+// during training. The handlers are stored in the model file after the
+// model, so that they do not have to be constructed when the tagger is
+// started. Handlers that are not used by the configuration are nil.
+type PrecomputedHandlers struct {
+	SuffixHandler       *words.SuffixHandler
+	LookupSuffixHandler *words.LookupSuffixHandler
+}
+
+// PrecomputeHandlers constructs the suffix handlers that are used by the
+// unknown word handler of the configuration.
+func (c CitarConfig) PrecomputeHandlers(m model.Model) PrecomputedHandlers {
+	var handlers PrecomputedHandlers
+
+	if !c.UsesSuffixHandler() {
+		return handlers
+	}
+
+	sh := words.NewSuffixHandler(c.SuffixHandler, m)
+	if c.usesHandler("tree") {
+		handlers.SuffixHandler = &sh
+	}
+
+	if c.usesHandler("lookup", "prefix_suffix", "compound", "cluster") {
+		lsh := words.NewLookupSuffixHandler(sh)
+		handlers.LookupSuffixHandler = &lsh
+	}
+
+	return handlers
+}
+
+// UsePrecomputedHandlers uses the given precomputed handlers rather than
+// constructing them. Precomputed handlers that were constructed with a
+// different suffix handler configuration are not used, an error is
+// returned to inform the user that the handlers are constructed instead.
+// Configurations are compared using SuffixHandlerConfig.Equal.
+func (c *CitarConfig) UsePrecomputedHandlers(handlers PrecomputedHandlers) error {
+	c.precomputed = PrecomputedHandlers{}
+
+	var mismatch bool
+	if h := handlers.SuffixHandler; h != nil {
+		if h.Config().Equal(c.SuffixHandler) {
+			c.precomputed.SuffixHandler = h
+		} else {
+			mismatch = true
+		}
+	}
+
+	if h := handlers.LookupSuffixHandler; h != nil {
+		if h.Config().Equal(c.SuffixHandler) {
+			c.precomputed.LookupSuffixHandler = h
+		} else {
+			mismatch = true
+		}
+	}
+
+	if mismatch {
+		return fmt.Errorf("the suffix handler configuration differs from the configuration used during training")
+	}
+
+	return nil
+}
+
+// UsesSuffixHandler returns true if the unknown word handler uses the
+// suffix handler, directly or as a component.
+func (c CitarConfig) UsesSuffixHandler() bool {
+	return c.usesHandler("tree", "lookup", "prefix_suffix", "compound", "cluster")
+}
+
+// usesHandler returns true if the unknown word handler is one of the
+// given handlers, or interpolates one of them.
+func (c CitarConfig) usesHandler(names ...string) bool {
+	for _, name := range names {
+		if c.UnknownHandler == name {
+			return true
+		}
+
+		if c.UnknownHandler != "interpolated" {
+			continue
+		}
+
+		for _, handler := range c.Interpolation.Handlers {
+			if handler.Name == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (c CitarConfig) suffixHandler(m model.Model) words.SuffixHandler {
+	if c.precomputed.SuffixHandler != nil {
+		return *c.precomputed.SuffixHandler
+	}
+
+	return words.NewSuffixHandler(c.SuffixHandler, m)
+}
+
+func (c CitarConfig) lookupSuffixHandler(m model.Model) words.LookupSuffixHandler {
+	if c.precomputed.LookupSuffixHandler != nil {
+		return *c.precomputed.LookupSuffixHandler
+	}
+
+	return words.NewLookupSuffixHandler(words.NewSuffixHandler(c.SuffixHandler, m))
+}
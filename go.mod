@@ -1,6 +1,9 @@
 module github.com/danieldk/citar
 
+go 1.26.0
+
 require (
 	github.com/BurntSushi/toml v0.3.0
 	github.com/danieldk/conllx v1.0.0
+	golang.org/x/text v0.42.0
 )
@@ -0,0 +1,12 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package lemma provides lemmatization of tagged words.
+//
+// Words that were seen with a tag in the training data receive the most
+// frequent lemma of the word/tag pair. The lemmas of other words are
+// predicted using edit scripts that rewrite the suffix of a word into the
+// suffix of its lemma. The edit scripts are learned per tag from the
+// lemmas in the training data.
+package lemma
@@ -0,0 +1,85 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lemma
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// EditScript rewrites a word form into its lemma. If Lowercase is set,
+// the first character of the form is lowercased. Then Delete characters
+// are removed from the end of the form and Insert is appended.
+type EditScript struct {
+	Lowercase bool
+	Delete    int
+	Insert    string
+}
+
+// NewEditScript constructs the edit script that rewrites a form into the
+// given lemma. The script keeps the longest common prefix of the form and
+// the lemma.
+func NewEditScript(form, lemma string) EditScript {
+	var script EditScript
+
+	formFirst, _ := utf8.DecodeRuneInString(form)
+	lemmaFirst, _ := utf8.DecodeRuneInString(lemma)
+	if formFirst != lemmaFirst && unicode.IsUpper(formFirst) && unicode.ToLower(formFirst) == lemmaFirst {
+		script.Lowercase = true
+		form = lowercaseFirst(form)
+	}
+
+	formRunes := []rune(form)
+	lemmaRunes := []rune(lemma)
+
+	prefixLen := 0
+	for prefixLen < len(formRunes) && prefixLen < len(lemmaRunes) &&
+		formRunes[prefixLen] == lemmaRunes[prefixLen] {
+		prefixLen++
+	}
+
+	script.Delete = len(formRunes) - prefixLen
+	script.Insert = string(lemmaRunes[prefixLen:])
+
+	return script
+}
+
+// Apply applies the edit script to a form. The second return value is
+// false if the form is too short to apply the script.
+func (s EditScript) Apply(form string) (string, bool) {
+	if s.Lowercase {
+		form = lowercaseFirst(form)
+	}
+
+	runes := []rune(form)
+	if s.Delete > len(runes) {
+		return form, false
+	}
+
+	return string(runes[:len(runes)-s.Delete]) + s.Insert, true
+}
+
+// less orders edit scripts, to break ties between equally frequent
+// scripts deterministically. Scripts that change less are ordered first.
+func (s EditScript) less(other EditScript) bool {
+	if s.Lowercase != other.Lowercase {
+		return !s.Lowercase
+	}
+
+	if s.Delete != other.Delete {
+		return s.Delete < other.Delete
+	}
+
+	return s.Insert < other.Insert
+}
+
+func lowercaseFirst(word string) string {
+	first, size := utf8.DecodeRuneInString(word)
+	if first == utf8.RuneError {
+		return word
+	}
+
+	return string(unicode.ToLower(first)) + word[size:]
+}
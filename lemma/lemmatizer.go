@@ -0,0 +1,167 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lemma
+
+import (
+	"fmt"
+
+	"github.com/danieldk/citar/model"
+)
+
+// LemmatizerConfig stores the configuration of a Lemmatizer. MaxSuffixLen
+// is the length of the longest word suffix for which edit scripts are
+// learned.
+type LemmatizerConfig struct {
+	MaxSuffixLen int `toml:"max_suffix_len"`
+}
+
+// DefaultLemmatizerConfig returns the default configuration of the
+// lemmatizer.
+func DefaultLemmatizerConfig() LemmatizerConfig {
+	return LemmatizerConfig{
+		MaxSuffixLen: 6,
+	}
+}
+
+// Validate checks that the configuration is valid.
+func (c LemmatizerConfig) Validate() error {
+	if c.MaxSuffixLen < 0 {
+		return fmt.Errorf("maximum suffix length should not be negative: %d", c.MaxSuffixLen)
+	}
+
+	return nil
+}
+
+// Lemmatizer assigns lemmas to tagged words. Word/tag pairs that occur in
+// the lemmas of the model receive their most frequent lemma. For other
+// words, the edit script of the longest suffix of the word that was
+// observed with the tag is applied. The edit scripts are learned from the
+// word types in the lemmas of the model: for every suffix of a word (up
+// to the maximum suffix length) that contains the characters that are
+// deleted by the edit script of the word, the edit script is counted for
+// that suffix and tag. The most frequent edit script of a suffix and tag
+// is used.
+type Lemmatizer struct {
+	lemmas       map[string]map[string]string
+	scripts      map[string]map[string]EditScript
+	maxSuffixLen int
+}
+
+type suffixTag struct {
+	suffix string
+	tag    string
+}
+
+// NewLemmatizer constructs a lemmatizer from the lemmas of a model.
+func NewLemmatizer(config LemmatizerConfig, m model.Model) Lemmatizer {
+	lemmas := make(map[string]map[string]string)
+	scriptFreqs := make(map[suffixTag]map[EditScript]int)
+
+	for form, tagLemmas := range m.LemmaFreqs() {
+		lemmas[form] = make(map[string]string)
+
+		for tag, lemmaFreqs := range tagLemmas {
+			lemma := mostFrequentLemma(lemmaFreqs)
+			lemmas[form][tag] = lemma
+
+			script := NewEditScript(form, lemma)
+			runes := []rune(form)
+			for suffixLen := script.Delete; suffixLen <= config.MaxSuffixLen && suffixLen <= len(runes); suffixLen++ {
+				key := suffixTag{string(runes[len(runes)-suffixLen:]), tag}
+				freqs, ok := scriptFreqs[key]
+				if !ok {
+					freqs = make(map[EditScript]int)
+					scriptFreqs[key] = freqs
+				}
+				freqs[script]++
+			}
+		}
+	}
+
+	scripts := make(map[string]map[string]EditScript)
+	for key, freqs := range scriptFreqs {
+		suffixScripts, ok := scripts[key.tag]
+		if !ok {
+			suffixScripts = make(map[string]EditScript)
+			scripts[key.tag] = suffixScripts
+		}
+
+		suffixScripts[key.suffix] = mostFrequentScript(freqs)
+	}
+
+	return Lemmatizer{
+		lemmas:       lemmas,
+		scripts:      scripts,
+		maxSuffixLen: config.MaxSuffixLen,
+	}
+}
+
+// Lemma returns the lemma of a word with the given tag. If the lemma
+// cannot be predicted, because no lemmas were observed with the tag, the
+// word itself is returned.
+func (l Lemmatizer) Lemma(word, tag string) string {
+	if lemma, ok := l.lemmas[word][tag]; ok {
+		return lemma
+	}
+
+	// Capitalized words at the beginning of a sentence.
+	if lemma, ok := l.lemmas[lowercaseFirst(word)][tag]; ok {
+		return lemma
+	}
+
+	suffixScripts, ok := l.scripts[tag]
+	if !ok {
+		return word
+	}
+
+	runes := []rune(word)
+	suffixLen := l.maxSuffixLen
+	if suffixLen > len(runes) {
+		suffixLen = len(runes)
+	}
+
+	for ; suffixLen >= 0; suffixLen-- {
+		script, ok := suffixScripts[string(runes[len(runes)-suffixLen:])]
+		if !ok {
+			continue
+		}
+
+		if lemma, ok := script.Apply(word); ok {
+			return lemma
+		}
+	}
+
+	return word
+}
+
+// mostFrequentLemma returns the most frequent lemma. Ties are broken by
+// choosing the lemma that sorts first.
+func mostFrequentLemma(lemmaFreqs map[string]int) string {
+	var best string
+	bestFreq := 0
+	for lemma, freq := range lemmaFreqs {
+		if freq > bestFreq || (freq == bestFreq && lemma < best) {
+			best = lemma
+			bestFreq = freq
+		}
+	}
+
+	return best
+}
+
+// mostFrequentScript returns the most frequent edit script. Ties are
+// broken using the ordering of edit scripts.
+func mostFrequentScript(scriptFreqs map[EditScript]int) EditScript {
+	var best EditScript
+	bestFreq := 0
+	for script, freq := range scriptFreqs {
+		if freq > bestFreq || (freq == bestFreq && script.less(best)) {
+			best = script
+			bestFreq = freq
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,140 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Capitalization determines how the capitalization of words is modelled
+// in the tag set.
+type Capitalization string
+
+const (
+	// CapitalizationOff does not model capitalization.
+	CapitalizationOff Capitalization = "off"
+
+	// CapitalizationBit models capitalization using the Capital field of
+	// Tag, so that each tag has a variant for capitalized and for
+	// uncapitalized words (Brants, 2000).
+	CapitalizationBit Capitalization = "bit"
+
+	// CapitalizationPrefix models capitalization by prefixing the tags
+	// of capitalized words with CapitalPrefix and the tags of other words
+	// with NonCapitalPrefix. The prefixes are removed from the tags in
+	// the output of the tagger.
+	CapitalizationPrefix Capitalization = "prefix"
+)
+
+// Prefixes of tags when capitalization is modelled using CapitalizationPrefix.
+const (
+	CapitalPrefix    = "c-"
+	NonCapitalPrefix = "n-"
+)
+
+// Validate checks that the capitalization is valid.
+func (c Capitalization) Validate() error {
+	switch c {
+	case CapitalizationOff, CapitalizationBit, CapitalizationPrefix:
+		return nil
+	}
+
+	return fmt.Errorf("unknown capitalization: %s (expected %s, %s, or %s)", c,
+		CapitalizationOff, CapitalizationBit, CapitalizationPrefix)
+}
+
+// Capitalization returns how capitalization is modelled in the tag set of
+// the model. Models that do not record the capitalization (models that
+// were trained before it could be configured) use CapitalizationBit.
+func (m Model) Capitalization() Capitalization {
+	if m.capitalization == "" {
+		return CapitalizationBit
+	}
+
+	return m.capitalization
+}
+
+// capitalizeLabel adds the capitalization prefix to a tag label, the
+// sentence boundary tags are never prefixed.
+func capitalizeLabel(label string, capital bool) string {
+	if label == StartToken || label == EndToken {
+		return label
+	}
+
+	if capital {
+		return CapitalPrefix + label
+	}
+
+	return NonCapitalPrefix + label
+}
+
+// BaseLabel returns the label of a tag, without the capitalization prefix.
+func (m Model) BaseLabel(t Tag) string {
+	label := m.tagNumberer.Label(t.Tag)
+	if m.Capitalization() != CapitalizationPrefix {
+		return label
+	}
+
+	if strings.HasPrefix(label, CapitalPrefix) {
+		return label[len(CapitalPrefix):]
+	}
+
+	return strings.TrimPrefix(label, NonCapitalPrefix)
+}
+
+// IsCapitalTag returns true if the tag is the variant of a tag for
+// capitalized words. If capitalization is not modelled, false is returned.
+func (m Model) IsCapitalTag(t Tag) bool {
+	switch m.Capitalization() {
+	case CapitalizationBit:
+		return t.Capital
+	case CapitalizationPrefix:
+		return strings.HasPrefix(m.tagNumberer.Label(t.Tag), CapitalPrefix)
+	}
+
+	return false
+}
+
+// CapitalVariant returns the variant of a tag for capitalized or
+// uncapitalized words. If capitalization is not modelled, the tag itself
+// is returned. The last return value is false if the variant does not
+// occur in the model.
+func (m Model) CapitalVariant(t Tag, capital bool) (Tag, bool) {
+	switch m.Capitalization() {
+	case CapitalizationBit:
+		t = Tag{Tag: t.Tag, Capital: capital}
+	case CapitalizationPrefix:
+		number, ok := m.tagNumberer.Lookup(capitalizeLabel(m.BaseLabel(t), capital))
+		if !ok {
+			return t, false
+		}
+		t = Tag{Tag: number}
+	}
+
+	_, ok := m.unigramFreqs[Unigram{T1: t}]
+	return t, ok
+}
+
+// TagNumbers returns the numbers of the tags with the given label, which
+// does not have a capitalization prefix. If capitalization is modelled
+// using prefixes, these are the numbers of the prefixed tags.
+func (m Model) TagNumbers(label string) []uint {
+	var labels []string
+	if m.Capitalization() == CapitalizationPrefix && label != StartToken && label != EndToken {
+		labels = []string{capitalizeLabel(label, true), capitalizeLabel(label, false)}
+	} else {
+		labels = []string{label}
+	}
+
+	var numbers []uint
+	for _, label := range labels {
+		if number, ok := m.tagNumberer.Lookup(label); ok {
+			numbers = append(numbers, number)
+		}
+	}
+
+	return numbers
+}
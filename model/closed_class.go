@@ -0,0 +1,56 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package model
+
+// InferClosedClassTags infers closed-class tags from the training data.
+// Closed-class tags (such as articles and prepositions) are assigned to
+// a small number of distinct word types, which occur frequently. A tag is
+// considered to be closed-class when it was assigned to at most maxTypes
+// word types and to at least minFreq tokens. The capitalization of tags
+// is ignored.
+func InferClosedClassTags(m Model, maxTypes, minFreq int) ClosedClassSet {
+	types := make(map[string]int)
+	for word, tagFreqs := range m.WordTagFreqs() {
+		if word == StartToken || word == EndToken {
+			continue
+		}
+
+		// Count a word once for the capitalized and uncapitalized variant
+		// of a tag.
+		labels := make(map[string]interface{})
+		for tag := range tagFreqs {
+			labels[m.BaseLabel(tag)] = nil
+		}
+
+		for label := range labels {
+			types[label]++
+		}
+	}
+
+	freqs := make(map[string]int)
+	for unigram, freq := range m.UnigramFreqs() {
+		freqs[m.BaseLabel(unigram.T1)] += freq
+	}
+
+	closedClass := make(ClosedClassSet)
+	for label, nTypes := range types {
+		if label == StartToken || label == EndToken {
+			continue
+		}
+
+		if nTypes <= maxTypes && freqs[label] >= minFreq {
+			closedClass[label] = nil
+		}
+	}
+
+	return closedClass
+}
+
+// WithClosedClassTags returns a copy of the model with the given
+// closed-class tags.
+func (m Model) WithClosedClassTags(closedClass ClosedClassSet) Model {
+	m.closedClass = closedClass
+	return m
+}
@@ -0,0 +1,112 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"fmt"
+	"sort"
+)
+
+// sortedWords returns the words of a lexicon in lexicographic order.
+func sortedWords(wordTagFreqs map[string]map[Tag]int) []string {
+	words := make([]string, 0, len(wordTagFreqs))
+	for word := range wordTagFreqs {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+
+	return words
+}
+
+// sortedTags returns the tags in a frequency map, ordered by tag number
+// and capitalization.
+func sortedTags(tagFreqs map[Tag]int) []Tag {
+	tags := make([]Tag, 0, len(tagFreqs))
+	for tag := range tagFreqs {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tagLess(tags[i], tags[j])
+	})
+
+	return tags
+}
+
+func sortedUnigrams(freqs map[Unigram]int) []Unigram {
+	unigrams := make([]Unigram, 0, len(freqs))
+	for unigram := range freqs {
+		unigrams = append(unigrams, unigram)
+	}
+	sort.Slice(unigrams, func(i, j int) bool {
+		return tagLess(unigrams[i].T1, unigrams[j].T1)
+	})
+
+	return unigrams
+}
+
+func sortedBigrams(freqs map[Bigram]int) []Bigram {
+	bigrams := make([]Bigram, 0, len(freqs))
+	for bigram := range freqs {
+		bigrams = append(bigrams, bigram)
+	}
+	sort.Slice(bigrams, func(i, j int) bool {
+		return tagsLess([]Tag{bigrams[i].T1, bigrams[i].T2},
+			[]Tag{bigrams[j].T1, bigrams[j].T2})
+	})
+
+	return bigrams
+}
+
+func sortedTrigrams(freqs map[Trigram]int) []Trigram {
+	trigrams := make([]Trigram, 0, len(freqs))
+	for trigram := range freqs {
+		trigrams = append(trigrams, trigram)
+	}
+	sort.Slice(trigrams, func(i, j int) bool {
+		return tagsLess([]Tag{trigrams[i].T1, trigrams[i].T2, trigrams[i].T3},
+			[]Tag{trigrams[j].T1, trigrams[j].T2, trigrams[j].T3})
+	})
+
+	return trigrams
+}
+
+func sortedClosedClass(closedClass ClosedClassSet) []string {
+	tags := make([]string, 0, len(closedClass))
+	for tag := range closedClass {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	return tags
+}
+
+func tagLess(t1, t2 Tag) bool {
+	if t1.Tag != t2.Tag {
+		return t1.Tag < t2.Tag
+	}
+
+	return !t1.Capital && t2.Capital
+}
+
+func tagsLess(tags1, tags2 []Tag) bool {
+	for i := range tags1 {
+		if tags1[i] != tags2[i] {
+			return tagLess(tags1[i], tags2[i])
+		}
+	}
+
+	return false
+}
+
+// tagNumber returns the number of a tag label that must be known to the
+// numberer.
+func tagNumber(numberer *StringNumberer, label string) (uint, error) {
+	number, ok := numberer.Lookup(label)
+	if !ok {
+		return 0, fmt.Errorf("unknown tag: %s", label)
+	}
+
+	return number, nil
+}
@@ -0,0 +1,209 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/danieldk/conllx"
+)
+
+var trainingSentences = [][][4]string{
+	{
+		{"Die", "ART", "der", "case:nom|number:sg"},
+		{"Katze", "NN", "Katze", "case:nom|number:sg"},
+		{"schläft", "VVFIN", "schlafen", ""},
+		{".", "$.", ".", ""},
+	},
+	{
+		{"Der", "ART", "der", "case:nom|number:sg"},
+		{"Hund", "NN", "Hund", "case:nom|number:sg"},
+		{"sieht", "VVFIN", "sehen", ""},
+		{"die", "ART", "der", "case:acc|number:pl"},
+		{"Katzen", "NN", "Katze", "case:acc|number:pl"},
+		{"1990", "CARD", "1990", ""},
+		{".", "$.", ".", ""},
+	},
+}
+
+func trainTestModel(t *testing.T, normalization Normalization,
+	capitalization Capitalization, features []string) Model {
+	fc := NewFrequencyCollector(FrequencyCollectorOptions{
+		Normalization:  normalization,
+		Capitalization: capitalization,
+		Features:       features,
+	})
+
+	for _, sentence := range trainingSentences {
+		tokens := make([]conllx.Token, len(sentence))
+		for i, columns := range sentence {
+			tokens[i].SetForm(columns[0]).SetPosTag(columns[1]).SetLemma(columns[2])
+			if columns[3] != "" {
+				features := make(map[string]string)
+				for _, feature := range strings.Split(columns[3], "|") {
+					av := strings.SplitN(feature, ":", 2)
+					features[av[0]] = av[1]
+				}
+				tokens[i].SetFeatures(features)
+			}
+		}
+
+		if err := fc.Process(tokens); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return fc.ModelWithClosedClass(ClosedClassSet{"ART": nil, "$.": nil})
+}
+
+// gobRoundTrip encodes a model as a gob and decodes it again, so that
+// the model is in the same state as a model that is read from a file.
+func gobRoundTrip(t *testing.T, m Model) Model {
+	data, err := m.GobEncode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Model
+	if err := decoded.GobDecode(data); err != nil {
+		t.Fatal(err)
+	}
+
+	return decoded
+}
+
+// checkGobEqual checks that two models have the same gob encoding. Since
+// gob does not encode maps in a fixed order, the encodings are compared
+// after decoding them.
+func checkGobEqual(t *testing.T, format string, expected, actual Model) {
+	decode := func(m Model) encodedModel {
+		data, err := m.GobEncode()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var em encodedModel
+		if err := gob.NewDecoder(bytes.NewBuffer(data)).Decode(&em); err != nil {
+			t.Fatal(err)
+		}
+
+		return em
+	}
+
+	expectedEncoding := decode(expected)
+	actualEncoding := decode(actual)
+
+	if !reflect.DeepEqual(expectedEncoding.TagNumberer.labels, actualEncoding.TagNumberer.labels) {
+		t.Errorf("%s: tag order differs: %v != %v", format, expectedEncoding.TagNumberer.labels,
+			actualEncoding.TagNumberer.labels)
+	}
+
+	if !reflect.DeepEqual(expectedEncoding.ClosedClass, actualEncoding.ClosedClass) {
+		t.Errorf("%s: closed-class tags differ: %v != %v", format, expectedEncoding.ClosedClass,
+			actualEncoding.ClosedClass)
+	}
+
+	if !reflect.DeepEqual(expectedEncoding, actualEncoding) {
+		t.Errorf("%s: model differs after round trip", format)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	configs := []struct {
+		name           string
+		normalization  Normalization
+		capitalization Capitalization
+		features       []string
+	}{
+		{"default", Normalization{}, CapitalizationBit, nil},
+		{"normalized", Normalization{Form: "NFKC", CaseFold: true, Digits: "zero"}, CapitalizationOff, nil},
+		{"prefix_features", Normalization{}, CapitalizationPrefix, []string{"case", "number"}},
+	}
+
+	for _, config := range configs {
+		t.Run(config.name, func(t *testing.T) {
+			m := gobRoundTrip(t, trainTestModel(t, config.normalization,
+				config.capitalization, config.features))
+
+			var buf bytes.Buffer
+			if err := m.WriteJSON(&buf); err != nil {
+				t.Fatal(err)
+			}
+			fromJSON, err := ReadJSON(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGobEqual(t, "JSON", m, fromJSON)
+
+			dir := t.TempDir()
+			if err := m.WriteTSV(dir); err != nil {
+				t.Fatal(err)
+			}
+			fromTSV, err := ReadTSV(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGobEqual(t, "TSV", m, fromTSV)
+		})
+	}
+}
+
+func TestTSVOverwrite(t *testing.T) {
+	dir := t.TempDir()
+
+	full := gobRoundTrip(t, trainTestModel(t, Normalization{CaseFold: true},
+		CapitalizationPrefix, []string{"case", "number"}))
+	if err := full.WriteTSV(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	plain := gobRoundTrip(t, trainTestModel(t, Normalization{}, CapitalizationBit, nil))
+	if err := plain.WriteTSV(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	fromTSV, err := ReadTSV(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGobEqual(t, "TSV", plain, fromTSV)
+}
+
+func TestTnTRoundTrip(t *testing.T) {
+	var lex, ngrams bytes.Buffer
+	withCapitalization := trainTestModel(t, Normalization{}, CapitalizationBit, nil)
+	if err := withCapitalization.WriteTnT(&lex, &ngrams); err == nil {
+		t.Error("model with capitalization was written in TnT format")
+	}
+
+	m := trainTestModel(t, Normalization{}, CapitalizationOff, nil)
+	lex.Reset()
+	ngrams.Reset()
+	if err := m.WriteTnT(&lex, &ngrams); err != nil {
+		t.Fatal(err)
+	}
+	expectedLex, expectedNGrams := lex.String(), ngrams.String()
+
+	fromTnT, err := ReadTnT(&lex, &ngrams, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromTnT.Capitalization() != CapitalizationOff {
+		t.Errorf("TnT model has capitalization: %s", fromTnT.Capitalization())
+	}
+
+	lex.Reset()
+	ngrams.Reset()
+	if err := fromTnT.WriteTnT(&lex, &ngrams); err != nil {
+		t.Fatal(err)
+	}
+	if lex.String() != expectedLex || ngrams.String() != expectedNGrams {
+		t.Error("TnT model differs after round trip")
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"strings"
+
+	"github.com/danieldk/conllx"
+)
+
+// FeatureSeparator separates the part-of-speech tag and the features in
+// a composite tag label, as well as the features themselves. Features are
+// written in the CoNLL-X attribute:value format, e.g. NN|case:nom|number:sg.
+const FeatureSeparator = "|"
+
+// Features returns the names of the morphological features that are
+// part of the tag labels of the model. If the model was trained on
+// part-of-speech tags only, the list is empty.
+func (m Model) Features() []string {
+	return m.features
+}
+
+// ComposeLabel composes a tag label from a part-of-speech tag and the
+// values of the given features. Features that are not in the feature map
+// are omitted from the label.
+func ComposeLabel(pos string, features map[string]string, names []string) string {
+	label := pos
+	for _, name := range names {
+		if value, ok := features[name]; ok {
+			label += FeatureSeparator + name + ":" + value
+		}
+	}
+
+	return label
+}
+
+// SplitLabel splits a composite tag label into the part-of-speech tag and
+// the features. The features are returned in the format of the CoNLL-X
+// features column, the features string is empty if the label does not
+// have features.
+func SplitLabel(label string) (pos, features string) {
+	if idx := strings.Index(label, FeatureSeparator); idx != -1 {
+		return label[:idx], label[idx+len(FeatureSeparator):]
+	}
+
+	return label, ""
+}
+
+// TokenLabel returns the tag label of a token in the training data. If
+// the model has features, the label is composed from the part-of-speech
+// tag and the features of the token.
+func (m Model) TokenLabel(token conllx.Token) (string, bool) {
+	pos, ok := token.PosTag()
+	if !ok {
+		return "", false
+	}
+
+	return composeTokenLabel(token, pos, m.features), true
+}
+
+func composeTokenLabel(token conllx.Token, pos string, names []string) string {
+	if len(names) == 0 {
+		return pos
+	}
+
+	var features map[string]string
+	if f, ok := token.Features(); ok {
+		features = f.FeaturesMap()
+	}
+
+	return ComposeLabel(pos, features, names)
+}
+
+// IsClosedClassTag returns true if the tag is a closed-class tag. If the
+// model has features, the tag is also a closed-class tag when its
+// part-of-speech is a closed-class tag.
+func (m Model) IsClosedClassTag(t Tag) bool {
+	label := m.BaseLabel(t)
+	if _, ok := m.closedClass[label]; ok {
+		return true
+	}
+
+	if len(m.features) == 0 {
+		return false
+	}
+
+	pos, _ := SplitLabel(label)
+	_, ok := m.closedClass[pos]
+	return ok
+}
@@ -0,0 +1,231 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+type jsonModel struct {
+	Tags        []string                 `json:"tags"`
+	ClosedClass []string                 `json:"closed_class"`
+	Lexicon     map[string][]jsonTagFreq `json:"lexicon"`
+	Unigrams    []jsonNGram              `json:"unigrams"`
+	Bigrams     []jsonNGram              `json:"bigrams"`
+	Trigrams    []jsonNGram              `json:"trigrams"`
+
+	Normalization  *Normalization             `json:"normalization,omitempty"`
+	Capitalization Capitalization             `json:"capitalization,omitempty"`
+	Lemmas         map[string][]jsonLemmaFreq `json:"lemmas,omitempty"`
+	Features       []string                   `json:"features,omitempty"`
+}
+
+type jsonTagFreq struct {
+	Tag     string `json:"tag"`
+	Capital bool   `json:"capital,omitempty"`
+	Freq    int    `json:"freq"`
+}
+
+type jsonLemmaFreq struct {
+	Tag   string `json:"tag"`
+	Lemma string `json:"lemma"`
+	Freq  int    `json:"freq"`
+}
+
+type jsonTag struct {
+	Tag     string `json:"tag"`
+	Capital bool   `json:"capital,omitempty"`
+}
+
+type jsonNGram struct {
+	Tags []jsonTag `json:"tags"`
+	Freq int       `json:"freq"`
+}
+
+// WriteJSON writes the model as a human-readable JSON document. The
+// output is deterministic: words, tags, and n-grams are written in
+// a fixed order.
+func (m Model) WriteJSON(writer io.Writer) error {
+	jm := jsonModel{
+		Tags:        m.tagNumberer.labels,
+		ClosedClass: sortedClosedClass(m.closedClass),
+		Lexicon:     make(map[string][]jsonTagFreq),
+	}
+
+	if !m.normalization.IsIdentity() {
+		normalization := m.normalization
+		jm.Normalization = &normalization
+	}
+
+	if m.Capitalization() != CapitalizationBit {
+		jm.Capitalization = m.Capitalization()
+	}
+
+	for word, tagFreqs := range m.wordTagFreqs {
+		entries := make([]jsonTagFreq, 0, len(tagFreqs))
+		for _, tag := range sortedTags(tagFreqs) {
+			entries = append(entries, jsonTagFreq{
+				Tag:     m.tagNumberer.Label(tag.Tag),
+				Capital: tag.Capital,
+				Freq:    tagFreqs[tag],
+			})
+		}
+		jm.Lexicon[word] = entries
+	}
+
+	jm.Features = m.features
+
+	if len(m.lemmaFreqs) != 0 {
+		jm.Lemmas = make(map[string][]jsonLemmaFreq)
+		for _, entry := range sortedLemmaEntries(m.lemmaFreqs) {
+			jm.Lemmas[entry.word] = append(jm.Lemmas[entry.word], jsonLemmaFreq{
+				Tag:   entry.tag,
+				Lemma: entry.lemma,
+				Freq:  entry.freq,
+			})
+		}
+	}
+
+	for _, unigram := range sortedUnigrams(m.unigramFreqs) {
+		jm.Unigrams = append(jm.Unigrams, m.jsonNGram(m.unigramFreqs[unigram], unigram.T1))
+	}
+
+	for _, bigram := range sortedBigrams(m.bigramFreqs) {
+		jm.Bigrams = append(jm.Bigrams, m.jsonNGram(m.bigramFreqs[bigram],
+			bigram.T1, bigram.T2))
+	}
+
+	for _, trigram := range sortedTrigrams(m.trigramFreqs) {
+		jm.Trigrams = append(jm.Trigrams, m.jsonNGram(m.trigramFreqs[trigram],
+			trigram.T1, trigram.T2, trigram.T3))
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(jm)
+}
+
+func (m Model) jsonNGram(freq int, tags ...Tag) jsonNGram {
+	ngram := jsonNGram{Freq: freq}
+	for _, tag := range tags {
+		ngram.Tags = append(ngram.Tags, jsonTag{
+			Tag:     m.tagNumberer.Label(tag.Tag),
+			Capital: tag.Capital,
+		})
+	}
+
+	return ngram
+}
+
+// ReadJSON reads a model that was written using WriteJSON. The tag
+// numberer of the model is frozen.
+func ReadJSON(reader io.Reader) (Model, error) {
+	var jm jsonModel
+	if err := json.NewDecoder(reader).Decode(&jm); err != nil {
+		return Model{}, err
+	}
+
+	numberer := NewStringStringNumberer()
+	for _, label := range jm.Tags {
+		if _, ok := numberer.Lookup(label); ok {
+			return Model{}, fmt.Errorf("duplicate tag: %s", label)
+		}
+		numberer.Number(label)
+	}
+
+	closedClass := make(ClosedClassSet)
+	for _, tag := range jm.ClosedClass {
+		closedClass[tag] = nil
+	}
+
+	wordTagFreqs := make(map[string]map[Tag]int)
+	for word, entries := range jm.Lexicon {
+		tagFreqs := make(map[Tag]int)
+		for _, entry := range entries {
+			number, err := tagNumber(numberer, entry.Tag)
+			if err != nil {
+				return Model{}, fmt.Errorf("lexicon entry for '%s': %s", word, err)
+			}
+			tagFreqs[Tag{number, entry.Capital}] = entry.Freq
+		}
+		wordTagFreqs[word] = tagFreqs
+	}
+
+	unigramFreqs := make(map[Unigram]int)
+	for _, ngram := range jm.Unigrams {
+		tags, err := jsonNGramTags(numberer, ngram, 1)
+		if err != nil {
+			return Model{}, err
+		}
+		unigramFreqs[Unigram{tags[0]}] = ngram.Freq
+	}
+
+	bigramFreqs := make(map[Bigram]int)
+	for _, ngram := range jm.Bigrams {
+		tags, err := jsonNGramTags(numberer, ngram, 2)
+		if err != nil {
+			return Model{}, err
+		}
+		bigramFreqs[Bigram{tags[0], tags[1]}] = ngram.Freq
+	}
+
+	trigramFreqs := make(map[Trigram]int)
+	for _, ngram := range jm.Trigrams {
+		tags, err := jsonNGramTags(numberer, ngram, 3)
+		if err != nil {
+			return Model{}, err
+		}
+		trigramFreqs[Trigram{tags[0], tags[1], tags[2]}] = ngram.Freq
+	}
+
+	numberer.Freeze()
+
+	m := newModel(numberer, wordTagFreqs, unigramFreqs, bigramFreqs,
+		trigramFreqs, closedClass)
+
+	if jm.Normalization != nil {
+		if err := jm.Normalization.Validate(); err != nil {
+			return Model{}, err
+		}
+		m.normalization = *jm.Normalization
+	}
+
+	if jm.Capitalization != "" {
+		if err := jm.Capitalization.Validate(); err != nil {
+			return Model{}, err
+		}
+		m.capitalization = jm.Capitalization
+	}
+
+	m.features = jm.Features
+
+	for word, entries := range jm.Lemmas {
+		for _, entry := range entries {
+			m.lemmaFreqs.add(word, entry.Tag, entry.Lemma, entry.Freq)
+		}
+	}
+
+	return m, nil
+}
+
+func jsonNGramTags(numberer *StringNumberer, ngram jsonNGram, order int) ([]Tag, error) {
+	if len(ngram.Tags) != order {
+		return nil, fmt.Errorf("expected n-gram of order %d, got %d tags", order, len(ngram.Tags))
+	}
+
+	tags := make([]Tag, 0, order)
+	for _, tag := range ngram.Tags {
+		number, err := tagNumber(numberer, tag.Tag)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, Tag{number, tag.Capital})
+	}
+
+	return tags, nil
+}
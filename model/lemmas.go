@@ -0,0 +1,71 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package model
+
+import "sort"
+
+// LemmaFreqs stores the frequencies of the lemmas of word/tag pairs in the
+// training data, indexed by word, tag, and lemma. The words are not
+// normalized, since the lemma depends on the form of a word. The tags are
+// the labels in the tagger output, i.e. without capitalization prefixes.
+type LemmaFreqs map[string]map[string]map[string]int
+
+func (l LemmaFreqs) add(word, tag, lemma string, freq int) {
+	tagLemmas, ok := l[word]
+	if !ok {
+		tagLemmas = make(map[string]map[string]int)
+		l[word] = tagLemmas
+	}
+
+	lemmas, ok := tagLemmas[tag]
+	if !ok {
+		lemmas = make(map[string]int)
+		tagLemmas[tag] = lemmas
+	}
+
+	lemmas[lemma] += freq
+}
+
+// LemmaFreqs returns the lemma frequencies of word/tag pairs in the
+// training data. The map is empty when the training data did not contain
+// lemmas.
+func (m Model) LemmaFreqs() LemmaFreqs {
+	return m.lemmaFreqs
+}
+
+// lemmaEntry is a word/tag/lemma triple with its frequency.
+type lemmaEntry struct {
+	word  string
+	tag   string
+	lemma string
+	freq  int
+}
+
+// sortedLemmaEntries returns the lemma frequencies as a list of entries,
+// sorted by word, tag, and lemma.
+func sortedLemmaEntries(lemmaFreqs LemmaFreqs) []lemmaEntry {
+	var entries []lemmaEntry
+	for word, tagLemmas := range lemmaFreqs {
+		for tag, lemmas := range tagLemmas {
+			for lemma, freq := range lemmas {
+				entries = append(entries, lemmaEntry{word, tag, lemma, freq})
+			}
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].word != entries[j].word {
+			return entries[i].word < entries[j].word
+		}
+
+		if entries[i].tag != entries[j].tag {
+			return entries[i].tag < entries[j].tag
+		}
+
+		return entries[i].lemma < entries[j].lemma
+	})
+
+	return entries
+}
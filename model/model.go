@@ -17,21 +17,29 @@ var _ gob.GobDecoder = &Model{}
 
 // Model stores a model of the training data.
 type Model struct {
-	tagNumberer  *StringNumberer
-	wordTagFreqs map[string]map[Tag]int
-	unigramFreqs map[Unigram]int
-	bigramFreqs  map[Bigram]int
-	trigramFreqs map[Trigram]int
-	closedClass  ClosedClassSet
+	tagNumberer    *StringNumberer
+	wordTagFreqs   map[string]map[Tag]int
+	unigramFreqs   map[Unigram]int
+	bigramFreqs    map[Bigram]int
+	trigramFreqs   map[Trigram]int
+	closedClass    ClosedClassSet
+	normalization  Normalization
+	capitalization Capitalization
+	lemmaFreqs     LemmaFreqs
+	features       []string
 }
 
 type encodedModel struct {
-	TagNumberer  *StringNumberer
-	WordTagFreqs map[string]map[Tag]int
-	UnigramFreqs map[Unigram]int
-	BigramFreqs  map[Bigram]int
-	TrigramFreqs map[Trigram]int
-	ClosedClass  ClosedClassSet
+	TagNumberer    *StringNumberer
+	WordTagFreqs   map[string]map[Tag]int
+	UnigramFreqs   map[Unigram]int
+	BigramFreqs    map[Bigram]int
+	TrigramFreqs   map[Trigram]int
+	ClosedClass    ClosedClassSet
+	Normalization  Normalization
+	Capitalization Capitalization
+	LemmaFreqs     LemmaFreqs
+	Features       []string
 }
 
 func newModel(tagNumberer *StringNumberer, wordTagFreqs map[string]map[Tag]int,
@@ -44,6 +52,9 @@ func newModel(tagNumberer *StringNumberer, wordTagFreqs map[string]map[Tag]int,
 		bigramFreqs:  bigramFreqs,
 		trigramFreqs: trigramFreqs,
 		closedClass:  closedClass,
+		lemmaFreqs:   make(LemmaFreqs),
+
+		capitalization: CapitalizationBit,
 	}
 }
 
@@ -71,6 +82,13 @@ func (m Model) TrigramFreqs() map[Trigram]int {
 	return m.trigramFreqs
 }
 
+// Normalization returns the normalization that was applied to the words
+// in the training data. The same normalization should be applied to
+// words before they are looked up.
+func (m Model) Normalization() Normalization {
+	return m.normalization
+}
+
 // TagNumberer returns the tag <-> number bijection.
 func (m Model) TagNumberer() *StringNumberer {
 	return m.tagNumberer
@@ -82,7 +100,8 @@ func (m Model) String() string {
 		len(m.unigramFreqs), len(m.bigramFreqs), len(m.trigramFreqs))
 }
 
-// GobDecode decodes a Model from a gob.
+// GobDecode decodes a Model from a gob. The tag numberer of the decoded
+// model is frozen.
 func (m *Model) GobDecode(data []byte) error {
 	var em encodedModel
 	buf := bytes.NewBuffer(data)
@@ -97,6 +116,24 @@ func (m *Model) GobDecode(data []byte) error {
 	m.bigramFreqs = em.BigramFreqs
 	m.trigramFreqs = em.TrigramFreqs
 	m.closedClass = em.ClosedClass
+	m.normalization = em.Normalization
+	m.capitalization = em.Capitalization
+	m.lemmaFreqs = em.LemmaFreqs
+	m.features = em.Features
+
+	// Models that were trained before the capitalization could be
+	// configured use the default.
+	if m.capitalization == "" {
+		m.capitalization = CapitalizationBit
+	}
+
+	if m.lemmaFreqs == nil {
+		m.lemmaFreqs = make(LemmaFreqs)
+	}
+
+	if m.tagNumberer != nil {
+		m.tagNumberer.Freeze()
+	}
 
 	return nil
 }
@@ -104,12 +141,16 @@ func (m *Model) GobDecode(data []byte) error {
 // GobEncode encodes a Model as a gob.
 func (m Model) GobEncode() ([]byte, error) {
 	em := encodedModel{
-		TagNumberer:  m.tagNumberer,
-		WordTagFreqs: m.wordTagFreqs,
-		UnigramFreqs: m.unigramFreqs,
-		BigramFreqs:  m.bigramFreqs,
-		TrigramFreqs: m.trigramFreqs,
-		ClosedClass:  m.closedClass,
+		TagNumberer:    m.tagNumberer,
+		WordTagFreqs:   m.wordTagFreqs,
+		UnigramFreqs:   m.unigramFreqs,
+		BigramFreqs:    m.bigramFreqs,
+		TrigramFreqs:   m.trigramFreqs,
+		ClosedClass:    m.closedClass,
+		Normalization:  m.normalization,
+		Capitalization: m.capitalization,
+		LemmaFreqs:     m.lemmaFreqs,
+		Features:       m.features,
 	}
 
 	var buf bytes.Buffer
@@ -0,0 +1,129 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalization describes how words are normalized before they are
+// counted during training or looked up during tagging. The normalization
+// is stored in the model, so that training and tagging agree.
+//
+// Form is the Unicode normalization form (NFC, NFD, NFKC, or NFKD), no
+// Unicode normalization is applied when Form is empty. If CaseFold is
+// true, full Unicode case folding is applied. If UnifyPunctuation is
+// true, typographic quotes and dashes are replaced by their ASCII
+// counterparts. Digits is "ascii" to replace decimal digits of all
+// scripts by ASCII digits or "zero" to replace all decimal digits by 0.
+type Normalization struct {
+	Form             string `toml:"form" json:"form,omitempty"`
+	CaseFold         bool   `toml:"case_fold" json:"case_fold,omitempty"`
+	UnifyPunctuation bool   `toml:"unify_punctuation" json:"unify_punctuation,omitempty"`
+	Digits           string `toml:"digits" json:"digits,omitempty"`
+}
+
+var normForms = map[string]norm.Form{
+	"NFC":  norm.NFC,
+	"NFD":  norm.NFD,
+	"NFKC": norm.NFKC,
+	"NFKD": norm.NFKD,
+}
+
+// Validate checks that the normalization is valid.
+func (n Normalization) Validate() error {
+	if _, ok := normForms[n.Form]; !ok && n.Form != "" {
+		return fmt.Errorf("unknown Unicode normalization form: %s", n.Form)
+	}
+
+	if n.Digits != "" && n.Digits != "ascii" && n.Digits != "zero" {
+		return fmt.Errorf("unknown digit normalization: %s", n.Digits)
+	}
+
+	return nil
+}
+
+// IsIdentity returns true if the normalization does not change words.
+func (n Normalization) IsIdentity() bool {
+	return n == Normalization{}
+}
+
+// Normalize normalizes a word. The sentence boundary markers are never
+// normalized.
+func (n Normalization) Normalize(word string) string {
+	if n.IsIdentity() || word == StartToken || word == EndToken {
+		return word
+	}
+
+	if n.CaseFold {
+		word = cases.Fold().String(word)
+	}
+
+	if n.UnifyPunctuation || n.Digits != "" {
+		var buf bytes.Buffer
+		for _, r := range word {
+			if n.UnifyPunctuation {
+				if replacement, ok := punctuationReplacements[r]; ok {
+					buf.WriteString(replacement)
+					continue
+				}
+			}
+
+			if n.Digits != "" {
+				if digit, ok := decimalDigit(r); ok {
+					if n.Digits == "zero" {
+						digit = 0
+					}
+					buf.WriteRune('0' + digit)
+					continue
+				}
+			}
+
+			buf.WriteRune(r)
+		}
+		word = buf.String()
+	}
+
+	if form, ok := normForms[n.Form]; ok {
+		word = form.String(word)
+	}
+
+	return word
+}
+
+var punctuationReplacements = map[rune]string{
+	'‘': "'", '’': "'", '‚': "'", '‛': "'", '′': "'", '‹': "'", '›': "'",
+	'“': "\"", '”': "\"", '„': "\"", '‟': "\"", '″': "\"", '«': "\"", '»': "\"",
+	'‐': "-", '‑': "-", '‒': "-", '–': "-", '—': "-", '―': "-", '−': "-",
+	'…': "...",
+}
+
+// decimalDigit returns the value of a decimal digit of any script.
+// Unicode decimal digits are encoded in contiguous ranges from zero to
+// nine.
+func decimalDigit(r rune) (rune, bool) {
+	if !unicode.IsDigit(r) {
+		return 0, false
+	}
+
+	for _, r16 := range unicode.Nd.R16 {
+		if rune(r16.Lo) <= r && r <= rune(r16.Hi) {
+			return (r - rune(r16.Lo)) % 10, true
+		}
+	}
+
+	for _, r32 := range unicode.Nd.R32 {
+		if rune(r32.Lo) <= r && r <= rune(r32.Hi) {
+			return (r - rune(r32.Lo)) % 10, true
+		}
+	}
+
+	return 0, false
+}
@@ -18,22 +18,33 @@ var _ gob.GobDecoder = &StringNumberer{}
 
 // A StringNumberer creates a bijection between (string-based) labels
 // and numbers.
+//
+// A StringNumberer can be frozen, after which no new labels can be added.
+// Code that only reads from a StringNumberer (e.g. when tagging) should use
+// Lookup rather than Number, since Number adds unknown labels.
 type StringNumberer struct {
 	labelNumbers map[string]uint
 	labels       []string
+	frozen       bool
 }
 
 // NewStringStringNumberer creates a new StringNumberer that is empty (it
 // has no mappings yet).
 func NewStringStringNumberer() *StringNumberer {
-	return &StringNumberer{make(map[string]uint), make([]string, 0)}
+	return &StringNumberer{make(map[string]uint), make([]string, 0), false}
 }
 
-// Number returns the (unique) number for for a label (string).
+// Number returns the (unique) number for for a label (string). If the
+// label is not known yet, it is added to the bijection. Number panics
+// when an unknown label is added to a frozen StringNumberer.
 func (l *StringNumberer) Number(label string) uint {
 	idx, ok := l.labelNumbers[label]
 
 	if !ok {
+		if l.frozen {
+			panic(fmt.Sprintf("cannot add label to frozen numberer: %s", label))
+		}
+
 		idx = uint(len(l.labelNumbers))
 		l.labelNumbers[label] = idx
 		l.labels = append(l.labels, label)
@@ -42,6 +53,24 @@ func (l *StringNumberer) Number(label string) uint {
 	return idx
 }
 
+// Lookup returns the number for a label (string). In contrast to Number,
+// Lookup never modifies the bijection. The second return value is false
+// when the label is unknown.
+func (l *StringNumberer) Lookup(label string) (uint, bool) {
+	idx, ok := l.labelNumbers[label]
+	return idx, ok
+}
+
+// Freeze freezes the bijection, so that no new labels can be added.
+func (l *StringNumberer) Freeze() {
+	l.frozen = true
+}
+
+// Frozen returns true if the bijection is frozen.
+func (l *StringNumberer) Frozen() bool {
+	return l.frozen
+}
+
 // Label returns the label (string) for a number.
 func (l *StringNumberer) Label(number uint) string {
 	return l.labels[number]
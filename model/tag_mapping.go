@@ -0,0 +1,59 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TagMapping maps tags from a fine-grained tag set to a coarse-grained
+// tag set, such as STTS to Universal POS tags.
+type TagMapping map[string]string
+
+// ReadTagMapping reads a tag mapping from a Reader. Each non-empty line
+// consists of a fine-grained tag and the corresponding coarse-grained tag,
+// separated by whitespace.
+func ReadTagMapping(reader io.Reader) (TagMapping, error) {
+	mapping := make(TagMapping)
+
+	scanner := bufio.NewScanner(reader)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected fine and coarse tag", lineno)
+		}
+
+		if _, ok := mapping[fields[0]]; ok {
+			return nil, fmt.Errorf("line %d: duplicate mapping for tag: %s", lineno, fields[0])
+		}
+
+		mapping[fields[0]] = fields[1]
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return mapping, nil
+}
+
+// Map returns the coarse-grained tag for a fine-grained tag. The sentence
+// boundary tags are always mapped to themselves. The second return value
+// is false when the tag cannot be mapped.
+func (m TagMapping) Map(tag string) (string, bool) {
+	if tag == StartToken || tag == EndToken {
+		return tag, true
+	}
+
+	coarse, ok := m[tag]
+	return coarse, ok
+}
@@ -0,0 +1,326 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ReadTnT reads a model from TnT parameter files: a lexicon (.lex) and
+// n-gram counts (.123). TnT does not distinguish tags of capitalized
+// words, so all tags in the resulting model have Capital set to false and
+// the capitalization of the model is CapitalizationOff.
+//
+// citar relies on the sentence boundary tags StartToken and EndToken.
+// TnT models are trained on a token stream without such tags. If the
+// model does not contain the boundary tags, they are synthesized from
+// boundaryTag, a tag that marks the end of a sentence (e.g. '$.' in
+// STTS): every occurrence of boundaryTag is treated as the end of a
+// sentence that is followed by the start of a new sentence. An error is
+// returned when the boundary tags are absent and boundaryTag is empty.
+//
+// The tag numberer of the model is frozen.
+func ReadTnT(lexReader, ngramReader io.Reader, boundaryTag string) (Model, error) {
+	numberer := NewStringStringNumberer()
+	unigramFreqs := make(map[Unigram]int)
+	bigramFreqs := make(map[Bigram]int)
+	trigramFreqs := make(map[Trigram]int)
+
+	var t1, t2 Tag
+	var order int
+	err := readTnTFile(ngramReader, func(lineno int, line string) error {
+		depth := len(line) - len(strings.TrimLeft(line, "\t"))
+		if depth > 2 {
+			return fmt.Errorf(".123 line %d: n-gram order larger than 3", lineno)
+		}
+		if depth > order {
+			return fmt.Errorf(".123 line %d: n-gram without context", lineno)
+		}
+
+		fields := tntFields(line)
+		if len(fields) != 2 {
+			return fmt.Errorf(".123 line %d: expected tag and frequency", lineno)
+		}
+
+		freq, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return fmt.Errorf(".123 line %d: invalid frequency: %s", lineno, fields[1])
+		}
+
+		tag := Tag{numberer.Number(fields[0]), false}
+
+		switch depth {
+		case 0:
+			t1 = tag
+			unigramFreqs[Unigram{t1}] += freq
+		case 1:
+			t2 = tag
+			bigramFreqs[Bigram{t1, t2}] += freq
+		case 2:
+			trigramFreqs[Trigram{t1, t2, tag}] += freq
+		}
+		order = depth + 1
+
+		return nil
+	})
+	if err != nil {
+		return Model{}, err
+	}
+
+	wordTagFreqs := make(map[string]map[Tag]int)
+	err = readTnTFile(lexReader, func(lineno int, line string) error {
+		fields := tntFields(line)
+		if len(fields) < 4 || len(fields)%2 != 0 {
+			return fmt.Errorf(".lex line %d: expected word, frequency, and tag/frequency pairs", lineno)
+		}
+
+		tagFreqs := make(map[Tag]int)
+		for i := 2; i < len(fields); i += 2 {
+			number, err := tagNumber(numberer, fields[i])
+			if err != nil {
+				return fmt.Errorf(".lex line %d: %s", lineno, err)
+			}
+
+			freq, err := strconv.Atoi(fields[i+1])
+			if err != nil {
+				return fmt.Errorf(".lex line %d: invalid frequency: %s", lineno, fields[i+1])
+			}
+
+			tagFreqs[Tag{number, false}] += freq
+		}
+
+		wordTagFreqs[fields[0]] = tagFreqs
+
+		return nil
+	})
+	if err != nil {
+		return Model{}, err
+	}
+
+	m := newModel(numberer, wordTagFreqs, unigramFreqs, bigramFreqs, trigramFreqs,
+		make(ClosedClassSet))
+	m.capitalization = CapitalizationOff
+
+	_, hasStart := numberer.Lookup(StartToken)
+	_, hasEnd := numberer.Lookup(EndToken)
+	if !hasStart || !hasEnd {
+		if boundaryTag == "" {
+			return Model{}, fmt.Errorf("model does not contain sentence boundary tags and no boundary tag was given")
+		}
+
+		if err := m.addSentenceBoundaries(boundaryTag); err != nil {
+			return Model{}, err
+		}
+	}
+
+	numberer.Freeze()
+
+	return m, nil
+}
+
+// addSentenceBoundaries adds the StartToken and EndToken tags to a model,
+// treating every occurrence of the given tag as a sentence boundary.
+func (m Model) addSentenceBoundaries(boundaryTag string) error {
+	number, err := tagNumber(m.tagNumberer, boundaryTag)
+	if err != nil {
+		return fmt.Errorf("boundary tag: %s", err)
+	}
+
+	b := Tag{number, false}
+	n := m.unigramFreqs[Unigram{b}]
+	if n == 0 {
+		return fmt.Errorf("boundary tag does not occur in the model: %s", boundaryTag)
+	}
+
+	start := Tag{m.tagNumberer.Number(StartToken), false}
+	end := Tag{m.tagNumberer.Number(EndToken), false}
+
+	// Collect the statistics before adding the boundaries, to avoid
+	// picking up synthesized n-grams.
+	successors := make(map[Tag]int)
+	predecessors := make(map[Tag]int)
+	for bigram, freq := range m.bigramFreqs {
+		if bigram.T1 == b {
+			successors[bigram.T2] += freq
+		}
+		if bigram.T2 == b {
+			predecessors[bigram.T1] += freq
+		}
+	}
+
+	successorBigrams := make(map[Bigram]int)
+	for trigram, freq := range m.trigramFreqs {
+		if trigram.T1 == b {
+			successorBigrams[Bigram{trigram.T2, trigram.T3}] += freq
+		}
+	}
+
+	// Every sentence starts with two start markers and ends with an end
+	// marker, following the boundary tag.
+	m.unigramFreqs[Unigram{start}] += 2 * n
+	m.unigramFreqs[Unigram{end}] += n
+	m.bigramFreqs[Bigram{start, start}] += n
+	m.bigramFreqs[Bigram{b, end}] += n
+
+	for t, freq := range successors {
+		m.bigramFreqs[Bigram{start, t}] += freq
+		m.trigramFreqs[Trigram{start, start, t}] += freq
+	}
+
+	for bigram, freq := range successorBigrams {
+		m.trigramFreqs[Trigram{start, bigram.T1, bigram.T2}] += freq
+	}
+
+	for t, freq := range predecessors {
+		m.trigramFreqs[Trigram{t, b, end}] += freq
+	}
+
+	m.wordTagFreqs[StartToken] = map[Tag]int{start: 2 * n}
+	m.wordTagFreqs[EndToken] = map[Tag]int{end: n}
+
+	return nil
+}
+
+// WriteTnT writes the model as TnT parameter files: a lexicon (.lex) and
+// n-gram counts (.123). The sentence boundary tags are written as ordinary
+// tags. Models with word normalization cannot be written, since the TnT
+// format cannot store the normalization. The same holds for models with
+// features and for models that distinguish tags of capitalized words,
+// which should be trained with CapitalizationOff to be written in TnT
+// format. Lemmas are not written.
+func (m Model) WriteTnT(lexWriter, ngramWriter io.Writer) error {
+	if !m.normalization.IsIdentity() {
+		return fmt.Errorf("word normalization cannot be stored in TnT format")
+	}
+
+	if m.Capitalization() != CapitalizationOff {
+		return fmt.Errorf("capitalization cannot be stored in TnT format: %s", m.Capitalization())
+	}
+
+	if len(m.features) != 0 {
+		return fmt.Errorf("features cannot be stored in TnT format")
+	}
+
+	for _, label := range m.tagNumberer.labels {
+		if strings.ContainsAny(label, " \t\n") {
+			return fmt.Errorf("tag cannot be written in TnT format: %q", label)
+		}
+	}
+
+	lw := bufio.NewWriter(lexWriter)
+	for _, word := range sortedWords(m.wordTagFreqs) {
+		if len(word) == 0 || strings.ContainsAny(word, " \t\n") {
+			return fmt.Errorf("word cannot be written in TnT format: %q", word)
+		}
+
+		labelFreqs := make(map[string]int)
+		var total int
+		for tag, freq := range m.wordTagFreqs[word] {
+			labelFreqs[m.tagNumberer.Label(tag.Tag)] += freq
+			total += freq
+		}
+
+		fmt.Fprintf(lw, "%s\t%d", word, total)
+		for _, label := range sortedLabelsByFreq(labelFreqs) {
+			fmt.Fprintf(lw, "\t%s\t%d", label, labelFreqs[label])
+		}
+		fmt.Fprintln(lw)
+	}
+	if err := lw.Flush(); err != nil {
+		return err
+	}
+
+	unigrams := make(map[string]int)
+	for unigram, freq := range m.unigramFreqs {
+		unigrams[m.tagNumberer.Label(unigram.T1.Tag)] += freq
+	}
+
+	bigrams := make(map[string]map[string]int)
+	for bigram, freq := range m.bigramFreqs {
+		t1 := m.tagNumberer.Label(bigram.T1.Tag)
+		if _, ok := bigrams[t1]; !ok {
+			bigrams[t1] = make(map[string]int)
+		}
+		bigrams[t1][m.tagNumberer.Label(bigram.T2.Tag)] += freq
+	}
+
+	trigrams := make(map[[2]string]map[string]int)
+	for trigram, freq := range m.trigramFreqs {
+		t1t2 := [2]string{m.tagNumberer.Label(trigram.T1.Tag), m.tagNumberer.Label(trigram.T2.Tag)}
+		if _, ok := trigrams[t1t2]; !ok {
+			trigrams[t1t2] = make(map[string]int)
+		}
+		trigrams[t1t2][m.tagNumberer.Label(trigram.T3.Tag)] += freq
+	}
+
+	nw := bufio.NewWriter(ngramWriter)
+	for _, t1 := range sortedLabels(unigrams) {
+		fmt.Fprintf(nw, "%s\t%d\n", t1, unigrams[t1])
+		for _, t2 := range sortedLabels(bigrams[t1]) {
+			fmt.Fprintf(nw, "\t%s\t%d\n", t2, bigrams[t1][t2])
+			t1t2 := [2]string{t1, t2}
+			for _, t3 := range sortedLabels(trigrams[t1t2]) {
+				fmt.Fprintf(nw, "\t\t%s\t%d\n", t3, trigrams[t1t2][t3])
+			}
+		}
+	}
+
+	return nw.Flush()
+}
+
+func sortedLabels(freqs map[string]int) []string {
+	labels := make([]string, 0, len(freqs))
+	for label := range freqs {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	return labels
+}
+
+func sortedLabelsByFreq(freqs map[string]int) []string {
+	labels := sortedLabels(freqs)
+	sort.SliceStable(labels, func(i, j int) bool {
+		return freqs[labels[i]] > freqs[labels[j]]
+	})
+
+	return labels
+}
+
+// tntFields splits a line of a TnT parameter file into fields. Fields
+// are separated by one or more tabs.
+func tntFields(line string) []string {
+	var fields []string
+	for _, field := range strings.Split(line, "\t") {
+		if field != "" {
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
+
+// readTnTFile calls process for every line of a TnT parameter file that
+// is not empty or a comment (starting with '%%').
+func readTnTFile(reader io.Reader, process func(lineno int, line string) error) error {
+	scanner := bufio.NewScanner(reader)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimRight(scanner.Text(), " \r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "%%") {
+			continue
+		}
+
+		if err := process(lineno, line); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
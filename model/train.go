@@ -15,34 +15,79 @@ import (
 // A FrequencyCollector collects frequencies from the training corpus that
 // are relevant to a trigram HMM tagger.
 type FrequencyCollector struct {
-	numberer *StringNumberer
-	lexicon  map[string]map[Tag]int
-	unigrams map[Unigram]int
-	bigrams  map[Bigram]int
-	trigrams map[Trigram]int
+	numberer       *StringNumberer
+	lexicon        map[string]map[Tag]int
+	unigrams       map[Unigram]int
+	bigrams        map[Bigram]int
+	trigrams       map[Trigram]int
+	tagMapping     TagMapping
+	normalization  Normalization
+	capitalization Capitalization
+	lemmas         LemmaFreqs
+	features       []string
 }
 
-// NewFrequencyCollector constructs a FrequencyCollector instance.
-func NewFrequencyCollector() FrequencyCollector {
+// FrequencyCollectorOptions configures a FrequencyCollector. The zero
+// value collects the frequencies of the part-of-speech tags of the
+// training data as-is.
+type FrequencyCollectorOptions struct {
+	// TagMapping maps the part-of-speech tags of the training data. It is
+	// optional and can be nil. Processing a sentence fails when it
+	// contains a tag that cannot be mapped.
+	TagMapping TagMapping
+
+	// Normalization is applied to words. It is stored in the model, so
+	// that it can be applied to words during tagging.
+	Normalization Normalization
+
+	// Capitalization specifies how capitalization is modelled in the tag
+	// set. It is stored in the model. CapitalizationBit is used when it
+	// is empty.
+	Capitalization Capitalization
+
+	// Features are the names of the morphological features that are part
+	// of the composite tag labels (see ComposeLabel). They are stored in
+	// the model.
+	Features []string
+}
+
+// NewFrequencyCollector constructs a FrequencyCollector instance with the
+// given options.
+func NewFrequencyCollector(opts FrequencyCollectorOptions) FrequencyCollector {
+	capitalization := opts.Capitalization
+	if capitalization == "" {
+		capitalization = CapitalizationBit
+	}
+
 	return FrequencyCollector{
 		numberer: NewStringStringNumberer(),
 		lexicon:  make(map[string]map[Tag]int),
 		unigrams: make(map[Unigram]int),
 		bigrams:  make(map[Bigram]int),
 		trigrams: make(map[Trigram]int),
+
+		tagMapping:     opts.TagMapping,
+		normalization:  opts.Normalization,
+		capitalization: capitalization,
+		lemmas:         make(LemmaFreqs),
+		features:       opts.Features,
 	}
 }
 
 // Model returns the collected frequencies as a model.
 func (c FrequencyCollector) Model() Model {
-	return newModel(c.numberer, c.lexicon, c.unigrams, c.bigrams, c.trigrams,
-		make(ClosedClassSet))
+	return c.ModelWithClosedClass(make(ClosedClassSet))
 }
 
 // ModelWithClosedClass returns the collected frequencies as a model, the
 // closed class set can be used by e.g. word handlers.
 func (c FrequencyCollector) ModelWithClosedClass(closedClassTags ClosedClassSet) Model {
-	return newModel(c.numberer, c.lexicon, c.unigrams, c.bigrams, c.trigrams, closedClassTags)
+	m := newModel(c.numberer, c.lexicon, c.unigrams, c.bigrams, c.trigrams, closedClassTags)
+	m.normalization = c.normalization
+	m.capitalization = c.capitalization
+	m.lemmaFreqs = c.lemmas
+	m.features = c.features
+	return m
 }
 
 // Process a sentence.
@@ -59,6 +104,10 @@ func (c FrequencyCollector) Process(sentence []conllx.Token) error {
 			return err
 		}
 
+		if wordTags[i].lemma != "" {
+			c.lemmas.add(wordTags[i].form, wordTags[i].label, wordTags[i].lemma, 1)
+		}
+
 		c.addUnigram(wordTags[i])
 		if i > 0 {
 			c.addBigram(wordTags[i-1], wordTags[i])
@@ -75,6 +124,13 @@ type wordTag struct {
 	word    string
 	tag     uint
 	isUpper bool
+
+	// The form before normalization, the tag label without
+	// capitalization prefix, and the lemma (if available), used to
+	// collect lemma frequencies.
+	form  string
+	label string
+	lemma string
 }
 
 func (c FrequencyCollector) sentenceToWordTags(sentence []conllx.Token) ([]wordTag, error) {
@@ -91,12 +147,37 @@ func (c FrequencyCollector) sentenceToWordTags(sentence []conllx.Token) ([]wordT
 			return nil, fmt.Errorf("token does not contain a part-of-speech: %s", token)
 		}
 
+		if c.tagMapping != nil {
+			mapped, ok := c.tagMapping.Map(pos)
+			if !ok {
+				return nil, fmt.Errorf("no mapping for part-of-speech tag: %s", pos)
+			}
+			pos = mapped
+		}
+
+		pos = composeTokenLabel(token, pos, c.features)
+
 		first, _ := utf8.DecodeRuneInString(form)
 		if first == utf8.RuneError {
 			return nil, fmt.Errorf("invalid UTF-8 character in form: %s", form)
 		}
 
-		wordTags = append(wordTags, wordTag{form, c.numberer.Number(pos), unicode.IsUpper(first)})
+		label := pos
+		lemma, _ := token.Lemma()
+
+		// Capitalization is determined before normalization, since the
+		// normalization may fold case.
+		capital := unicode.IsUpper(first)
+		switch c.capitalization {
+		case CapitalizationOff:
+			capital = false
+		case CapitalizationPrefix:
+			pos = capitalizeLabel(pos, capital)
+			capital = false
+		}
+
+		wordTags = append(wordTags, wordTag{c.normalization.Normalize(form),
+			c.numberer.Number(pos), capital, form, label, lemma})
 	}
 
 	return wordTags, nil
@@ -150,27 +231,3 @@ func (c FrequencyCollector) addMarkers(sentence []conllx.Token) []conllx.Token {
 
 	return sentence
 }
-
-func (c FrequencyCollector) addCapitalTags(sentence []conllx.Token) error {
-	for i := 0; i < len(sentence); i++ {
-		form, ok := sentence[i].Form()
-		pos, pok := sentence[i].PosTag()
-
-		if ok && pok {
-			first, _ := utf8.DecodeRuneInString(form)
-			if first == utf8.RuneError {
-				return fmt.Errorf("invalid UTF-8 character in form: %s", form)
-			}
-
-			if unicode.IsUpper(first) {
-				sentence[i].SetPosTag(fmt.Sprintf("c-%s", pos))
-			} else {
-				sentence[i].SetPosTag(fmt.Sprintf("n-%s", pos))
-			}
-		} else {
-			return fmt.Errorf("token does not contain form or part-of-speech: %s", sentence[i])
-		}
-	}
-
-	return nil
-}
@@ -0,0 +1,463 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Names of the files in a model that is stored as tab-separated text.
+const (
+	TSVTagsFile        = "tags.txt"
+	TSVClosedClassFile = "closed-class.txt"
+	TSVLexiconFile     = "lexicon.tsv"
+	TSVUnigramsFile    = "unigrams.tsv"
+	TSVBigramsFile     = "bigrams.tsv"
+	TSVTrigramsFile    = "trigrams.tsv"
+
+	TSVNormalizationFile  = "normalization.tsv"
+	TSVCapitalizationFile = "capitalization.txt"
+	TSVLemmasFile         = "lemmas.tsv"
+	TSVFeaturesFile       = "features.txt"
+)
+
+// WriteTSV writes the model as a set of human-readable files to the given
+// directory, which is created when it does not exist:
+//
+// tags.txt contains the tag list, one tag per line, in the order of the
+// tag numbers. closed-class.txt contains the closed-class tags, one tag
+// per line. lexicon.tsv contains a line per word/tag pair, consisting of
+// the word, the tag, the capitalization marker, and the frequency.
+// unigrams.tsv, bigrams.tsv, and trigrams.tsv contain a tag/capitalization
+// column pair for each tag of the n-gram, followed by its frequency.
+// normalization.tsv contains the word normalization as option/value pairs,
+// it is only written when words are normalized. capitalization.txt
+// contains the capitalization model, it is only written when it differs
+// from the default (CapitalizationBit). lemmas.tsv contains a line per
+// word/tag/lemma triple, consisting of the word, the tag, the lemma, and
+// the frequency, it is only written when the model has lemmas.
+// features.txt contains the names of the features that are part of the
+// tag labels, one name per line, it is only written when the model has
+// features. Optional files that are not written are removed from the
+// directory.
+func (m Model) WriteTSV(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for _, label := range m.tagNumberer.labels {
+		if strings.ContainsAny(label, "\t\n") {
+			return fmt.Errorf("tag cannot be written as TSV: %q", label)
+		}
+	}
+
+	err := writeTSVFile(filepath.Join(dir, TSVTagsFile), func(w *bufio.Writer) error {
+		return m.tagNumberer.WriteStringStringNumberer(w)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeTSVFile(filepath.Join(dir, TSVClosedClassFile), func(w *bufio.Writer) error {
+		for _, tag := range sortedClosedClass(m.closedClass) {
+			fmt.Fprintln(w, tag)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeTSVFile(filepath.Join(dir, TSVLexiconFile), func(w *bufio.Writer) error {
+		for _, word := range sortedWords(m.wordTagFreqs) {
+			if strings.ContainsAny(word, "\t\n") {
+				return fmt.Errorf("word cannot be written as TSV: %q", word)
+			}
+
+			tagFreqs := m.wordTagFreqs[word]
+			for _, tag := range sortedTags(tagFreqs) {
+				fmt.Fprintf(w, "%s\t%s\t%d\n", word, m.tsvTags(tag), tagFreqs[tag])
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeTSVFile(filepath.Join(dir, TSVUnigramsFile), func(w *bufio.Writer) error {
+		for _, unigram := range sortedUnigrams(m.unigramFreqs) {
+			fmt.Fprintf(w, "%s\t%d\n", m.tsvTags(unigram.T1), m.unigramFreqs[unigram])
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeTSVFile(filepath.Join(dir, TSVBigramsFile), func(w *bufio.Writer) error {
+		for _, bigram := range sortedBigrams(m.bigramFreqs) {
+			fmt.Fprintf(w, "%s\t%d\n", m.tsvTags(bigram.T1, bigram.T2), m.bigramFreqs[bigram])
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeOptionalTSVFile(filepath.Join(dir, TSVNormalizationFile), !m.normalization.IsIdentity(),
+		func(w *bufio.Writer) error {
+			n := m.normalization
+			fmt.Fprintf(w, "form\t%s\n", n.Form)
+			fmt.Fprintf(w, "case_fold\t%t\n", n.CaseFold)
+			fmt.Fprintf(w, "unify_punctuation\t%t\n", n.UnifyPunctuation)
+			fmt.Fprintf(w, "digits\t%s\n", n.Digits)
+			return nil
+		})
+	if err != nil {
+		return err
+	}
+
+	err = writeOptionalTSVFile(filepath.Join(dir, TSVCapitalizationFile), m.Capitalization() != CapitalizationBit,
+		func(w *bufio.Writer) error {
+			fmt.Fprintln(w, m.Capitalization())
+			return nil
+		})
+	if err != nil {
+		return err
+	}
+
+	err = writeOptionalTSVFile(filepath.Join(dir, TSVFeaturesFile), len(m.features) != 0,
+		func(w *bufio.Writer) error {
+			for _, name := range m.features {
+				fmt.Fprintln(w, name)
+			}
+			return nil
+		})
+	if err != nil {
+		return err
+	}
+
+	err = writeOptionalTSVFile(filepath.Join(dir, TSVLemmasFile), len(m.lemmaFreqs) != 0,
+		func(w *bufio.Writer) error {
+			for _, entry := range sortedLemmaEntries(m.lemmaFreqs) {
+				if strings.ContainsAny(entry.word+entry.lemma, "\t\n") {
+					return fmt.Errorf("lemma cannot be written as TSV: %q", entry.lemma)
+				}
+
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", entry.word, entry.tag, entry.lemma, entry.freq)
+			}
+			return nil
+		})
+	if err != nil {
+		return err
+	}
+
+	return writeTSVFile(filepath.Join(dir, TSVTrigramsFile), func(w *bufio.Writer) error {
+		for _, trigram := range sortedTrigrams(m.trigramFreqs) {
+			fmt.Fprintf(w, "%s\t%d\n", m.tsvTags(trigram.T1, trigram.T2, trigram.T3),
+				m.trigramFreqs[trigram])
+		}
+		return nil
+	})
+}
+
+func (m Model) tsvTags(tags ...Tag) string {
+	columns := make([]string, 0, 2*len(tags))
+	for _, tag := range tags {
+		columns = append(columns, m.tagNumberer.Label(tag.Tag), strconv.FormatBool(tag.Capital))
+	}
+
+	return strings.Join(columns, "\t")
+}
+
+func writeTSVFile(filename string, write func(w *bufio.Writer) error) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if err := write(w); err != nil {
+		return err
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// writeOptionalTSVFile writes a file that is only part of the model when
+// present is true. Otherwise, the file is removed, so that a file from a
+// model that was previously written to the same directory is not read
+// back as part of this model.
+func writeOptionalTSVFile(filename string, present bool, write func(w *bufio.Writer) error) error {
+	if present {
+		return writeTSVFile(filename, write)
+	}
+
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// ReadTSV reads a model that was written to a directory using WriteTSV.
+// The tag numberer of the model is frozen.
+func ReadTSV(dir string) (Model, error) {
+	numberer := NewStringStringNumberer()
+	err := readTSVFile(filepath.Join(dir, TSVTagsFile), 1, func(columns []string) error {
+		if _, ok := numberer.Lookup(columns[0]); ok {
+			return fmt.Errorf("duplicate tag: %s", columns[0])
+		}
+		numberer.Number(columns[0])
+		return nil
+	})
+	if err != nil {
+		return Model{}, err
+	}
+
+	closedClass := make(ClosedClassSet)
+	err = readTSVFile(filepath.Join(dir, TSVClosedClassFile), 1, func(columns []string) error {
+		closedClass[columns[0]] = nil
+		return nil
+	})
+	if err != nil {
+		return Model{}, err
+	}
+
+	wordTagFreqs := make(map[string]map[Tag]int)
+	err = readTSVFile(filepath.Join(dir, TSVLexiconFile), 4, func(columns []string) error {
+		tags, freq, err := parseTSVNGram(numberer, columns[1:])
+		if err != nil {
+			return err
+		}
+
+		tagFreqs, ok := wordTagFreqs[columns[0]]
+		if !ok {
+			tagFreqs = make(map[Tag]int)
+			wordTagFreqs[columns[0]] = tagFreqs
+		}
+		tagFreqs[tags[0]] = freq
+
+		return nil
+	})
+	if err != nil {
+		return Model{}, err
+	}
+
+	unigramFreqs := make(map[Unigram]int)
+	err = readTSVFile(filepath.Join(dir, TSVUnigramsFile), 3, func(columns []string) error {
+		tags, freq, err := parseTSVNGram(numberer, columns)
+		if err != nil {
+			return err
+		}
+		unigramFreqs[Unigram{tags[0]}] = freq
+		return nil
+	})
+	if err != nil {
+		return Model{}, err
+	}
+
+	bigramFreqs := make(map[Bigram]int)
+	err = readTSVFile(filepath.Join(dir, TSVBigramsFile), 5, func(columns []string) error {
+		tags, freq, err := parseTSVNGram(numberer, columns)
+		if err != nil {
+			return err
+		}
+		bigramFreqs[Bigram{tags[0], tags[1]}] = freq
+		return nil
+	})
+	if err != nil {
+		return Model{}, err
+	}
+
+	trigramFreqs := make(map[Trigram]int)
+	err = readTSVFile(filepath.Join(dir, TSVTrigramsFile), 7, func(columns []string) error {
+		tags, freq, err := parseTSVNGram(numberer, columns)
+		if err != nil {
+			return err
+		}
+		trigramFreqs[Trigram{tags[0], tags[1], tags[2]}] = freq
+		return nil
+	})
+	if err != nil {
+		return Model{}, err
+	}
+
+	numberer.Freeze()
+
+	m := newModel(numberer, wordTagFreqs, unigramFreqs, bigramFreqs,
+		trigramFreqs, closedClass)
+
+	m.normalization, err = readTSVNormalization(filepath.Join(dir, TSVNormalizationFile))
+	if err != nil {
+		return Model{}, err
+	}
+
+	m.capitalization, err = readTSVCapitalization(filepath.Join(dir, TSVCapitalizationFile))
+	if err != nil {
+		return Model{}, err
+	}
+
+	m.features, err = readTSVFeatures(filepath.Join(dir, TSVFeaturesFile))
+	if err != nil {
+		return Model{}, err
+	}
+
+	if err := readTSVLemmas(filepath.Join(dir, TSVLemmasFile), m.lemmaFreqs); err != nil {
+		return Model{}, err
+	}
+
+	return m, nil
+}
+
+// readTSVFeatures reads the feature names. The file is optional, if it
+// does not exist, the model does not have features.
+func readTSVFeatures(filename string) ([]string, error) {
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var features []string
+	err := readTSVFile(filename, 1, func(columns []string) error {
+		features = append(features, columns[0])
+		return nil
+	})
+
+	return features, err
+}
+
+// readTSVLemmas reads the lemma frequencies. The file is optional, if it
+// does not exist, the model does not have lemmas.
+func readTSVLemmas(filename string, lemmaFreqs LemmaFreqs) error {
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return nil
+	}
+
+	return readTSVFile(filename, 4, func(columns []string) error {
+		freq, err := strconv.Atoi(columns[3])
+		if err != nil {
+			return fmt.Errorf("invalid frequency: %s", columns[3])
+		}
+
+		lemmaFreqs.add(columns[0], columns[1], columns[2], freq)
+		return nil
+	})
+}
+
+// readTSVCapitalization reads the capitalization model. The file is
+// optional, if it does not exist, the default capitalization is used.
+func readTSVCapitalization(filename string) (Capitalization, error) {
+	capitalization := CapitalizationBit
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return capitalization, nil
+	}
+
+	err := readTSVFile(filename, 1, func(columns []string) error {
+		capitalization = Capitalization(columns[0])
+		return capitalization.Validate()
+	})
+
+	return capitalization, err
+}
+
+// readTSVNormalization reads the word normalization. The normalization
+// file is optional, if it does not exist, words are not normalized.
+func readTSVNormalization(filename string) (Normalization, error) {
+	var n Normalization
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return n, nil
+	}
+
+	err := readTSVFile(filename, 2, func(columns []string) error {
+		var err error
+		switch columns[0] {
+		case "form":
+			n.Form = columns[1]
+		case "case_fold":
+			n.CaseFold, err = strconv.ParseBool(columns[1])
+		case "unify_punctuation":
+			n.UnifyPunctuation, err = strconv.ParseBool(columns[1])
+		case "digits":
+			n.Digits = columns[1]
+		default:
+			return fmt.Errorf("unknown normalization option: %s", columns[0])
+		}
+		return err
+	})
+	if err != nil {
+		return n, err
+	}
+
+	return n, n.Validate()
+}
+
+// parseTSVNGram parses tag/capitalization column pairs, followed by a
+// frequency column.
+func parseTSVNGram(numberer *StringNumberer, columns []string) ([]Tag, int, error) {
+	var tags []Tag
+	for i := 0; i < len(columns)-1; i += 2 {
+		number, err := tagNumber(numberer, columns[i])
+		if err != nil {
+			return nil, 0, err
+		}
+
+		capital, err := strconv.ParseBool(columns[i+1])
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid capitalization marker: %s", columns[i+1])
+		}
+
+		tags = append(tags, Tag{number, capital})
+	}
+
+	freq, err := strconv.Atoi(columns[len(columns)-1])
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid frequency: %s", columns[len(columns)-1])
+	}
+
+	return tags, freq, nil
+}
+
+func readTSVFile(filename string, nColumns int, process func(columns []string) error) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if nColumns == 1 {
+			line = strings.TrimSpace(line)
+		}
+
+		if line == "" {
+			continue
+		}
+
+		columns := strings.Split(line, "\t")
+		if len(columns) != nColumns {
+			return fmt.Errorf("%s:%d: expected %d columns, got %d", filename, lineno,
+				nColumns, len(columns))
+		}
+
+		if err := process(columns); err != nil {
+			return fmt.Errorf("%s:%d: %s", filename, lineno, err)
+		}
+	}
+
+	return scanner.Err()
+}
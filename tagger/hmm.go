@@ -20,15 +20,14 @@ type Trellis struct {
 }
 
 // Tags returns the most likely part-of-speech tag sequence in the
-// Trellis.
+// Trellis. Capitalization prefixes are removed from the tags.
 func (t Trellis) Tags() ([]string, float64) {
 	tagNumbers, prob := t.highestProbabilitySequence()
-	tagNumberer := t.model.TagNumberer()
 
 	tags := make([]string, 0, len(tagNumbers))
 
 	for i := 2; i < len(tagNumbers)-1; i++ {
-		tag := tagNumberer.Label(tagNumbers[i])
+		tag := t.model.BaseLabel(model.Tag{Tag: tagNumbers[i]})
 		tags = append(tags, tag)
 	}
 
@@ -130,7 +129,10 @@ func (t HMMTagger) viterbi(sentence []string) []*trellisState {
 	var nextTrellis []*trellisState
 
 	// Prepare initial trellis states.
-	startTag := t.model.TagNumberer().Number(sentence[0])
+	startTag, ok := t.model.TagNumberer().Lookup(sentence[0])
+	if !ok {
+		panic(fmt.Sprintf("Model does not contain the start tag: %s", sentence[0]))
+	}
 	state1 := newTrellisState(model.Tag{Tag: startTag, Capital: false})
 	state2 := newTrellisState(model.Tag{Tag: startTag, Capital: false})
 	state2.backpointers[state1] = backpointer{nil, 0.0}
@@ -21,6 +21,7 @@ type trigramProbs map[model.Trigram]float64
 // using maximum likelihood estimation and linear interpolation smoothing
 // (Brants, 2000).
 type LinearInterpolationModel struct {
+	lambdas      smoothingParameters
 	unigramProbs unigramProbs
 	bigramProbs  bigramProbs
 	trigramProbs trigramProbs
@@ -34,6 +35,7 @@ func NewLinearInterpolationModel(model model.Model) LinearInterpolationModel {
 		model.TrigramFreqs())
 
 	return LinearInterpolationModel{
+		lambdas:      smoothingParameters,
 		unigramProbs: calcUnigramProbs(corpusSize, smoothingParameters, model.UnigramFreqs()),
 		bigramProbs: calcBigramProbs(corpusSize, smoothingParameters,
 			model.UnigramFreqs(), model.BigramFreqs()),
@@ -60,6 +62,12 @@ func (m LinearInterpolationModel) TrigramProb(trigram model.Trigram) float64 {
 	panic(fmt.Sprintf("Unknown tag: %v", trigram.T3))
 }
 
+// Lambdas returns the interpolation weights of the unigram, bigram, and
+// trigram probabilities.
+func (m LinearInterpolationModel) Lambdas() (float64, float64, float64) {
+	return m.lambdas.L1, m.lambdas.L2, m.lambdas.L3
+}
+
 func corpusSize(unigramFreqs map[model.Unigram]int) int {
 	var size int
 
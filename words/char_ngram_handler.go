@@ -0,0 +1,275 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package words
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/danieldk/citar/model"
+)
+
+// Padding symbols that mark the beginning and end of a word.
+const (
+	charNGramBOW = '\x02'
+	charNGramEOW = '\x03'
+)
+
+// CharNGramHandlerConfig stores the configuration of a CharNGramHandler.
+// Order is the order of the character n-gram models. The models are
+// trained on words with a frequency up to MaxFreq.
+type CharNGramHandlerConfig struct {
+	Order   int `toml:"order"`
+	MaxFreq int `toml:"max_freq"`
+	MaxTags int `toml:"max_tags"`
+}
+
+// DefaultCharNGramHandlerConfig returns a CharNGramHandlerConfig with
+// reasonable defaults.
+func DefaultCharNGramHandlerConfig() CharNGramHandlerConfig {
+	return CharNGramHandlerConfig{
+		Order:   4,
+		MaxFreq: 10,
+		MaxTags: 10,
+	}
+}
+
+// Validate checks that the configuration is valid.
+func (c CharNGramHandlerConfig) Validate() error {
+	if c.Order < 1 {
+		return fmt.Errorf("n-gram order should be at least 1: %d", c.Order)
+	}
+
+	if c.MaxFreq < 1 {
+		return fmt.Errorf("maximum frequency should be at least 1: %d", c.MaxFreq)
+	}
+
+	if c.MaxTags < 1 {
+		return fmt.Errorf("maximum number of tags should be at least 1: %d", c.MaxTags)
+	}
+
+	return nil
+}
+
+var _ WordHandler = CharNGramHandler{}
+
+// CharNGramHandler is an emission probability estimator for unknown words
+// that uses a character n-gram language model for each tag. In contrast
+// to suffix-based handlers, it also captures word-internal patterns.
+//
+// The emission probability of a word is estimated directly as:
+//
+// P(w|t) = P(rare|t) P(c_1 ... c_n|t)
+//
+// where P(rare|t) is the probability that a token with tag t is a
+// low-frequency word and P(c_1 ... c_n|t) is the probability of the
+// character sequence of the word according to the n-gram model of t.
+// The n-gram models are trained on the low-frequency word types of the
+// training data and smoothed using Witten-Bell interpolation. If the
+// training data does not contain low-frequency words, the handler assigns
+// the same probability to the most frequent open-class tags.
+type CharNGramHandler struct {
+	models    map[model.Tag]*charNGramModel
+	logRare   map[model.Tag]float64
+	fallback  map[model.Tag]float64
+	vocabSize int
+	maxTags   int
+}
+
+// NewCharNGramHandler trains a CharNGramHandler on the low-frequency
+// words in the model.
+func NewCharNGramHandler(config CharNGramHandlerConfig, m model.Model) CharNGramHandler {
+	skip := unknownWordSkipTags(m)
+
+	h := CharNGramHandler{
+		models:  make(map[model.Tag]*charNGramModel),
+		logRare: make(map[model.Tag]float64),
+		maxTags: config.MaxTags,
+	}
+
+	rareFreqs := make(map[model.Tag]int)
+	alphabet := make(map[rune]interface{})
+
+	for word, tagFreqs := range m.WordTagFreqs() {
+		if word == model.StartToken || word == model.EndToken || len(word) == 0 {
+			continue
+		}
+
+		var wordFreq int
+		for _, freq := range tagFreqs {
+			wordFreq += freq
+		}
+
+		if wordFreq > config.MaxFreq {
+			continue
+		}
+
+		runes := []rune(word)
+		for _, r := range runes {
+			alphabet[r] = nil
+		}
+
+		for tag, freq := range tagFreqs {
+			if _, ok := skip[tag.Tag]; ok {
+				continue
+			}
+
+			lm, ok := h.models[tag]
+			if !ok {
+				lm = newCharNGramModel(config.Order)
+				h.models[tag] = lm
+			}
+
+			lm.add(runes)
+			rareFreqs[tag] += freq
+		}
+	}
+
+	for tag, freq := range rareFreqs {
+		h.logRare[tag] = math.Log(float64(freq) / float64(m.UnigramFreqs()[model.Unigram{T1: tag}]))
+	}
+
+	// Characters that were not seen in training and the end of word
+	// symbol are also part of the vocabulary.
+	h.vocabSize = len(alphabet) + 2
+
+	if len(h.models) == 0 {
+		h.fallback = frequentTagProbs(m, skip, config.MaxTags)
+	}
+
+	return h
+}
+
+// frequentTagProbs returns a distribution that assigns the same emission
+// probability to the n most frequent tags that are not skipped. This is
+// the Bayesian inversion of P(t|w) = P(t), restricted to these tags.
+func frequentTagProbs(m model.Model, skip map[uint]interface{}, n int) map[model.Tag]float64 {
+	freqs := make(map[model.Tag]float64)
+	for unigram, freq := range m.UnigramFreqs() {
+		if _, ok := skip[unigram.T1.Tag]; !ok {
+			freqs[unigram.T1] = float64(freq)
+		}
+	}
+
+	probs := bestN(freqs, n)
+	for tag := range probs {
+		probs[tag] = -math.Log(float64(len(probs)))
+	}
+
+	return probs
+}
+
+// TagProbs returns P(w|t) for a particular word 'w'.
+func (h CharNGramHandler) TagProbs(word string) map[model.Tag]float64 {
+	if len(h.models) == 0 {
+		return copyTagProbs(h.fallback)
+	}
+
+	runes := []rune(word)
+
+	scores := make([]tagProb, 0, len(h.models))
+	for tag, lm := range h.models {
+		scores = append(scores, tagProb{
+			tag:  tag,
+			prob: h.logRare[tag] + lm.logProb(runes, h.vocabSize),
+		})
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		return scores[i].prob > scores[j].prob
+	})
+
+	if len(scores) > h.maxTags {
+		scores = scores[:h.maxTags]
+	}
+
+	probs := make(map[model.Tag]float64)
+	for _, score := range scores {
+		probs[score.tag] = score.prob
+	}
+
+	return probs
+}
+
+// charNGramModel is a character n-gram language model. It stores the
+// frequencies of characters given histories of length 0 up to order - 1.
+type charNGramModel struct {
+	order    int
+	contexts map[string]*charContext
+}
+
+type charContext struct {
+	total  int
+	counts map[rune]int
+}
+
+func newCharNGramModel(order int) *charNGramModel {
+	return &charNGramModel{
+		order:    order,
+		contexts: make(map[string]*charContext),
+	}
+}
+
+// pad adds beginning and end of word symbols to a word.
+func (m *charNGramModel) pad(runes []rune) []rune {
+	padded := make([]rune, 0, len(runes)+m.order)
+	for i := 0; i < m.order-1; i++ {
+		padded = append(padded, charNGramBOW)
+	}
+	padded = append(padded, runes...)
+	return append(padded, charNGramEOW)
+}
+
+func (m *charNGramModel) add(runes []rune) {
+	padded := m.pad(runes)
+
+	for i := m.order - 1; i < len(padded); i++ {
+		for k := 0; k < m.order; k++ {
+			history := string(padded[i-k : i])
+
+			ctx, ok := m.contexts[history]
+			if !ok {
+				ctx = &charContext{counts: make(map[rune]int)}
+				m.contexts[history] = ctx
+			}
+
+			ctx.counts[padded[i]]++
+			ctx.total++
+		}
+	}
+}
+
+// logProb returns the log probability of a word.
+func (m *charNGramModel) logProb(runes []rune, vocabSize int) float64 {
+	padded := m.pad(runes)
+
+	var logProb float64
+	for i := m.order - 1; i < len(padded); i++ {
+		logProb += math.Log(m.charProb(padded[i-m.order+1:i], padded[i], vocabSize))
+	}
+
+	return logProb
+}
+
+// charProb returns the probability of a character given its history,
+// using Witten-Bell interpolation of the histories of decreasing length
+// with the uniform distribution over the vocabulary.
+func (m *charNGramModel) charProb(history []rune, r rune, vocabSize int) float64 {
+	prob := 1 / float64(vocabSize)
+
+	for k := 0; k <= len(history); k++ {
+		// If a history was not seen, longer histories were not seen either.
+		ctx, ok := m.contexts[string(history[len(history)-k:])]
+		if !ok {
+			break
+		}
+
+		distinct := float64(len(ctx.counts))
+		prob = (float64(ctx.counts[r]) + distinct*prob) / (float64(ctx.total) + distinct)
+	}
+
+	return prob
+}
@@ -0,0 +1,227 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package words
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/danieldk/citar/model"
+)
+
+// WordClusters maps words to cluster identifiers. Clusters are typically
+// computed from a large raw corpus, e.g. Brown clusters or k-means
+// clusters of word embeddings.
+type WordClusters map[string]string
+
+// ReadWordClusters reads word clusters. Each line consists of a word and
+// its cluster identifier, separated by a tab. Alternatively, lines can be
+// in the format of the paths file of Brown clustering: the cluster (bit
+// string), the word, and the word frequency, separated by tabs. Empty
+// lines are ignored.
+func ReadWordClusters(reader io.Reader) (WordClusters, error) {
+	clusters := make(WordClusters)
+
+	scanner := bufio.NewScanner(reader)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		parts := strings.Split(line, "\t")
+		switch len(parts) {
+		case 2:
+			clusters[parts[0]] = parts[1]
+		case 3:
+			clusters[parts[1]] = parts[0]
+		default:
+			return nil, fmt.Errorf("line %d: expected word and cluster, or cluster, word, and frequency", lineno)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return clusters, nil
+}
+
+// ClusterHandlerConfig stores the configuration of a ClusterHandler. The
+// tag distribution of a cluster is estimated from the training words in
+// the cluster with a frequency up to MaxFreq (all words when MaxFreq is
+// zero). Weight is the weight of the cluster distribution when it is
+// interpolated with the distribution of the fallback handler.
+type ClusterHandlerConfig struct {
+	MaxFreq int     `toml:"max_freq"`
+	Weight  float64 `toml:"weight"`
+	MaxTags int     `toml:"max_tags"`
+}
+
+// DefaultClusterHandlerConfig returns a ClusterHandlerConfig with
+// reasonable defaults.
+func DefaultClusterHandlerConfig() ClusterHandlerConfig {
+	return ClusterHandlerConfig{
+		MaxFreq: 0,
+		Weight:  0.5,
+		MaxTags: 10,
+	}
+}
+
+// Validate checks that the configuration is valid.
+func (c ClusterHandlerConfig) Validate() error {
+	if c.MaxFreq < 0 {
+		return fmt.Errorf("maximum frequency should not be negative: %d", c.MaxFreq)
+	}
+
+	if c.Weight < 0 || c.Weight > 1 {
+		return fmt.Errorf("weight should be in [0, 1]: %f", c.Weight)
+	}
+
+	if c.MaxTags < 1 {
+		return fmt.Errorf("maximum number of tags should be at least 1: %d", c.MaxTags)
+	}
+
+	return nil
+}
+
+var _ WordHandler = ClusterHandler{}
+
+// ClusterHandler is an emission probability estimator for unknown words
+// that uses distributional word clusters. An unknown word is mapped to
+// its cluster and P(t|cluster) is estimated from the training words in
+// the same cluster. The emission probabilities that are obtained using
+// Bayesian inversion are interpolated with those of the fallback handler
+// (typically a SuffixHandler):
+//
+// P(w|t) = λ P_cluster(w|t) + (1 - λ) P_fallback(w|t)
+//
+// Words that are not in a cluster, or in a cluster without training
+// words, are handled by the fallback handler.
+type ClusterHandler struct {
+	model    model.Model
+	clusters WordClusters
+	tagProbs map[string]map[model.Tag]float64
+	fallback WordHandler
+	weight   float64
+	maxTags  int
+}
+
+// NewClusterHandler constructs a ClusterHandler from word clusters, a
+// model, and a fallback handler. The model's normalization is applied to
+// the words of the clusters.
+func NewClusterHandler(config ClusterHandlerConfig, clusters WordClusters, m model.Model,
+	fallback WordHandler) ClusterHandler {
+	normalized := make(WordClusters)
+	for word, cluster := range clusters {
+		normalized[m.Normalization().Normalize(word)] = cluster
+	}
+
+	skip := unknownWordSkipTags(m)
+
+	clusterTagFreqs := make(map[string]map[model.Tag]int)
+	for word, tagFreqs := range m.WordTagFreqs() {
+		cluster, ok := normalized[word]
+		if !ok {
+			continue
+		}
+
+		var wordFreq int
+		for _, freq := range tagFreqs {
+			wordFreq += freq
+		}
+
+		if config.MaxFreq != 0 && wordFreq > config.MaxFreq {
+			continue
+		}
+
+		freqs, ok := clusterTagFreqs[cluster]
+		if !ok {
+			freqs = make(map[model.Tag]int)
+			clusterTagFreqs[cluster] = freqs
+		}
+
+		for tag, freq := range tagFreqs {
+			if _, ok := skip[tag.Tag]; !ok {
+				freqs[tag] += freq
+			}
+		}
+	}
+
+	tagProbs := make(map[string]map[model.Tag]float64)
+	for cluster, freqs := range clusterTagFreqs {
+		var total float64
+		for _, freq := range freqs {
+			total += float64(freq)
+		}
+
+		if total == 0 {
+			continue
+		}
+
+		tp := make(map[model.Tag]float64)
+		for tag, freq := range freqs {
+			tp[tag] = float64(freq) / total
+		}
+
+		bayesianInversion(m.UnigramFreqs(), tp)
+		tagProbs[cluster] = tp
+	}
+
+	return ClusterHandler{
+		model:    m,
+		clusters: normalized,
+		tagProbs: tagProbs,
+		fallback: fallback,
+		weight:   config.Weight,
+		maxTags:  config.MaxTags,
+	}
+}
+
+// TagProbs estimates P(w|t) for a particular word 'w'.
+func (h ClusterHandler) TagProbs(word string) map[model.Tag]float64 {
+	cluster, ok := h.Cluster(word)
+	if !ok {
+		return h.fallback.TagProbs(word)
+	}
+
+	clusterProbs, ok := h.tagProbs[cluster]
+	if !ok {
+		return h.fallback.TagProbs(word)
+	}
+
+	// Prefer tags with the same capitalization as the word.
+	first, _ := utf8.DecodeRuneInString(word)
+	capital := unicode.IsUpper(first)
+	tp := make(map[model.Tag]float64)
+	for tag, prob := range clusterProbs {
+		if h.model.IsCapitalTag(tag) == capital {
+			tp[tag] = prob
+		}
+	}
+	if len(tp) == 0 {
+		tp = copyTagProbs(clusterProbs)
+	}
+
+	return interpolateTagProbs(
+		[]map[model.Tag]float64{bestNLogSpace(tp, h.maxTags), h.fallback.TagProbs(word)},
+		[]float64{h.weight, 1 - h.weight})
+}
+
+// Cluster returns the cluster of a word. If the word is not in a cluster,
+// the cluster of its lowercase variant is returned. The second return
+// value is false when neither is in a cluster.
+func (h ClusterHandler) Cluster(word string) (string, bool) {
+	if cluster, ok := h.clusters[word]; ok {
+		return cluster, true
+	}
+
+	cluster, ok := h.clusters[strings.ToLower(word)]
+	return cluster, ok
+}
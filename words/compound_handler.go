@@ -0,0 +1,230 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package words
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/danieldk/citar/model"
+)
+
+// Compound split strategies.
+const (
+	// CompoundLongestHead splits a word into a modifier and the longest
+	// head that is a known word.
+	CompoundLongestHead = "longest_head"
+
+	// CompoundKnownModifier splits a word into a modifier and the longest
+	// head that is a known word, where the modifier (after removal of a
+	// linking element) must also be a known word.
+	CompoundKnownModifier = "known_modifier"
+)
+
+// CompoundHandlerConfig stores the configuration of a CompoundHandler.
+// Strategy is the split strategy (CompoundLongestHead or
+// CompoundKnownModifier). The head and modifier of a compound should
+// have at least MinHeadLen and MinModifierLen characters. LinkingElements
+// are the linking elements that can occur between the modifier and the
+// head (such as -s- in German Arbeitsamt). Linking elements are only used
+// by the CompoundKnownModifier strategy, to look up the modifier without
+// its linking element. CompoundLongestHead does not check the modifier,
+// so a linking element can become part of the head (e.g. Arbeit+samt).
+type CompoundHandlerConfig struct {
+	Strategy        string   `toml:"strategy"`
+	MinHeadLen      int      `toml:"min_head_len"`
+	MinModifierLen  int      `toml:"min_modifier_len"`
+	LinkingElements []string `toml:"linking_elements"`
+	MaxTags         int      `toml:"max_tags"`
+}
+
+// DefaultCompoundHandlerConfig returns a CompoundHandlerConfig that is
+// suitable for German and Dutch.
+func DefaultCompoundHandlerConfig() CompoundHandlerConfig {
+	return CompoundHandlerConfig{
+		Strategy:        CompoundLongestHead,
+		MinHeadLen:      3,
+		MinModifierLen:  3,
+		LinkingElements: []string{"s", "es", "n", "en", "e"},
+		MaxTags:         10,
+	}
+}
+
+// Validate checks that the configuration is valid.
+func (c CompoundHandlerConfig) Validate() error {
+	if c.Strategy != CompoundLongestHead && c.Strategy != CompoundKnownModifier {
+		return fmt.Errorf("unknown split strategy: %s", c.Strategy)
+	}
+
+	if c.MinHeadLen < 1 {
+		return fmt.Errorf("minimum head length should be at least 1: %d", c.MinHeadLen)
+	}
+
+	if c.MinModifierLen < 1 {
+		return fmt.Errorf("minimum modifier length should be at least 1: %d", c.MinModifierLen)
+	}
+
+	if c.MaxTags < 1 {
+		return fmt.Errorf("maximum number of tags should be at least 1: %d", c.MaxTags)
+	}
+
+	return nil
+}
+
+var _ WordHandler = CompoundHandler{}
+
+// CompoundHandler is an emission probability estimator for unknown words
+// that are compounds of which the head is a known word. In languages such
+// as German and Dutch, most unknown words are compounds. The tag
+// distribution of a compound is estimated using the tag distribution of
+// its head. Words that cannot be split are handled by the fallback
+// handler (typically a SuffixHandler).
+type CompoundHandler struct {
+	config       CompoundHandlerConfig
+	model        model.Model
+	wordTagFreqs map[string]map[model.Tag]int
+	uf           map[model.Unigram]int
+	skip         map[uint]interface{}
+	fallback     WordHandler
+}
+
+// NewCompoundHandler constructs a new CompoundHandler from the given
+// configuration, model, and fallback handler.
+func NewCompoundHandler(config CompoundHandlerConfig, m model.Model, fallback WordHandler) CompoundHandler {
+	return CompoundHandler{
+		config:       config,
+		model:        m,
+		wordTagFreqs: m.WordTagFreqs(),
+		uf:           m.UnigramFreqs(),
+		skip:         unknownWordSkipTags(m),
+		fallback:     fallback,
+	}
+}
+
+// TagProbs estimates P(w|t) for a particular word 'w'.
+func (h CompoundHandler) TagProbs(word string) map[model.Tag]float64 {
+	if _, _, tp, ok := h.split(word); ok {
+		bayesianInversion(h.uf, tp)
+		return bestNLogSpace(tp, h.config.MaxTags)
+	}
+
+	return h.fallback.TagProbs(word)
+}
+
+// Split splits a word into its modifier and the form of its head that is
+// in the lexicon. The last return value is false when the word could not
+// be split.
+func (h CompoundHandler) Split(word string) (string, string, bool) {
+	modifier, head, _, ok := h.split(word)
+	return modifier, head, ok
+}
+
+// split splits a word as Split does, additionally returning P(t|head) for
+// the tags that the word can have, so that it is not computed twice.
+func (h CompoundHandler) split(word string) (string, string, map[model.Tag]float64, bool) {
+	runes := []rune(word)
+
+	// Heads are tried from long to short.
+	for i := h.config.MinModifierLen; i <= len(runes)-h.config.MinHeadLen; i++ {
+		head, ok := h.knownForm(string(runes[i:]))
+		if !ok {
+			continue
+		}
+
+		modifier := string(runes[:i])
+		if h.config.Strategy == CompoundKnownModifier && !h.knownModifier(modifier) {
+			continue
+		}
+
+		if tp := headTagProbs(h.model, h.skip, word, head); len(tp) != 0 {
+			return modifier, head, tp, true
+		}
+	}
+
+	return "", "", nil, false
+}
+
+// knownModifier checks whether a modifier is a known word, possibly after
+// removing a linking element.
+func (h CompoundHandler) knownModifier(modifier string) bool {
+	if _, ok := h.knownForm(modifier); ok {
+		return true
+	}
+
+	for _, linking := range h.config.LinkingElements {
+		if linking == "" || !strings.HasSuffix(modifier, linking) {
+			continue
+		}
+
+		stem := strings.TrimSuffix(modifier, linking)
+		if utf8.RuneCountInString(stem) < h.config.MinModifierLen {
+			continue
+		}
+
+		if _, ok := h.knownForm(stem); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// knownForm returns the form of a compound part that is in the lexicon.
+// Since the capitalization of a part can differ from its capitalization
+// as a separate word (e.g. the head of German noun compounds), the part
+// as-is, with an uppercase first letter, and in lowercase are tried.
+func (h CompoundHandler) knownForm(part string) (string, bool) {
+	if _, ok := h.wordTagFreqs[part]; ok {
+		return part, true
+	}
+
+	first, size := utf8.DecodeRuneInString(part)
+	if upper := string(unicode.ToUpper(first)) + part[size:]; upper != part {
+		if _, ok := h.wordTagFreqs[upper]; ok {
+			return upper, true
+		}
+	}
+
+	if lower := strings.ToLower(part); lower != part {
+		if _, ok := h.wordTagFreqs[lower]; ok {
+			return lower, true
+		}
+	}
+
+	return "", false
+}
+
+// headTagProbs returns P(t|head) for the tags that an unknown word with
+// the given head can have. The tags follow the capitalization of the word.
+func headTagProbs(m model.Model, skip map[uint]interface{}, word, head string) map[model.Tag]float64 {
+	first, _ := utf8.DecodeRuneInString(word)
+	capital := unicode.IsUpper(first)
+
+	tp := make(map[model.Tag]float64)
+	var total float64
+	for tag, freq := range m.WordTagFreqs()[head] {
+		if _, ok := skip[tag.Tag]; ok {
+			continue
+		}
+
+		// Only use tags that are compatible with the capitalization of
+		// the word.
+		tag, ok := m.CapitalVariant(tag, capital)
+		if !ok {
+			continue
+		}
+
+		tp[tag] += float64(freq)
+		total += float64(freq)
+	}
+
+	for tag, freq := range tp {
+		tp[tag] = freq / total
+	}
+
+	return tp
+}
@@ -0,0 +1,250 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package words
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/danieldk/citar/model"
+)
+
+// ExternalLexicon is a full-form lexicon, such as a morphological
+// dictionary, that maps words to their possible tags. If the lexicon
+// provides frequencies, they are stored as well, otherwise the frequency
+// of a word/tag pair is zero.
+type ExternalLexicon map[string]map[string]int
+
+// ReadExternalLexicon reads an external lexicon. Each line consists of a
+// word, a tag, and optionally a frequency, separated by tabs. A word can
+// have multiple lines, one for each tag. Empty lines and lines starting
+// with '#' are ignored. If none of the tags of a word has a frequency, the
+// probability mass of the word is distributed using the unigram tag
+// frequencies of the model. Otherwise, tags without a frequency (or with
+// frequency zero) are counted with frequency 1, so that every listed tag
+// can be assigned.
+func ReadExternalLexicon(reader io.Reader) (ExternalLexicon, error) {
+	lexicon := make(ExternalLexicon)
+
+	scanner := bufio.NewScanner(reader)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimRight(scanner.Text(), " \r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Split(line, "\t")
+		if len(parts) != 2 && len(parts) != 3 {
+			return nil, fmt.Errorf("line %d: expected word, tag, and optional frequency", lineno)
+		}
+
+		var freq int
+		if len(parts) == 3 {
+			var err error
+			freq, err = strconv.Atoi(parts[2])
+			if err != nil || freq < 0 {
+				return nil, fmt.Errorf("line %d: invalid frequency: %s", lineno, parts[2])
+			}
+		}
+
+		tags, ok := lexicon[parts[0]]
+		if !ok {
+			tags = make(map[string]int)
+			lexicon[parts[0]] = tags
+		}
+		tags[parts[1]] += freq
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return lexicon, nil
+}
+
+// ExternalLexiconMode determines how an ExternalLexiconHandler uses the
+// tags from the external lexicon.
+type ExternalLexiconMode int
+
+const (
+	// ExternalLexiconRestrict restricts the candidate tags of a word that
+	// is in the external lexicon to the tags in the external lexicon.
+	ExternalLexiconRestrict ExternalLexiconMode = iota
+
+	// ExternalLexiconAugment interpolates the distribution of the tags in
+	// the external lexicon with the distribution of the fallback handler.
+	ExternalLexiconAugment
+)
+
+// ParseExternalLexiconMode parses the name of an external lexicon mode
+// ("restrict" or "augment").
+func ParseExternalLexiconMode(mode string) (ExternalLexiconMode, error) {
+	switch mode {
+	case "restrict":
+		return ExternalLexiconRestrict, nil
+	case "augment":
+		return ExternalLexiconAugment, nil
+	}
+
+	return 0, fmt.Errorf("unknown external lexicon mode: %s", mode)
+}
+
+var _ WordHandler = ExternalLexiconHandler{}
+
+// ExternalLexiconHandler is an emission probability estimator that uses
+// an external lexicon for words that were not seen in the training data.
+//
+// The probability mass of a word in the external lexicon is distributed
+// over its tags using the frequencies in the external lexicon or, when the
+// lexicon does not provide frequencies, using the unigram tag frequencies
+// of the model. Emission probabilities are then obtained using Bayesian
+// inversion. Words that are not in the external lexicon are handled by
+// the fallback handler.
+type ExternalLexiconHandler struct {
+	lexicon  map[string]map[uint]int
+	model    model.Model
+	uf       map[model.Unigram]int
+	fallback WordHandler
+	mode     ExternalLexiconMode
+	weight   float64
+}
+
+// NewExternalLexiconHandler constructs an ExternalLexiconHandler. In the
+// ExternalLexiconAugment mode, weight is the weight of the external
+// lexicon distribution (in [0, 1]). Tags in the external lexicon that do
+// not occur in the model and closed-class tags are ignored, since the
+// handler is used for unknown words. The model's normalization is applied
+// to the words of the external lexicon. If fallback is nil, the handler
+// returns an empty distribution for words that are not in the external
+// lexicon and the mode is ignored. This is useful when the handler is a
+// component of an InterpolatedHandler.
+func NewExternalLexiconHandler(lexicon ExternalLexicon, m model.Model, fallback WordHandler,
+	mode ExternalLexiconMode, weight float64) ExternalLexiconHandler {
+	skip := unknownWordSkipTags(m)
+	numbered := make(map[string]map[uint]int)
+
+	for word, tags := range lexicon {
+		word = m.Normalization().Normalize(word)
+
+		numberedTags, ok := numbered[word]
+		if !ok {
+			numberedTags = make(map[uint]int)
+		}
+
+		for tag, freq := range tags {
+			// If capitalization is modelled using prefixes, the tag has
+			// two numbers. Use either, the capitalization is determined
+			// when the word is looked up.
+			numbers := m.TagNumbers(tag)
+			if len(numbers) == 0 {
+				continue
+			}
+
+			if _, ok := skip[numbers[0]]; !ok {
+				numberedTags[numbers[0]] += freq
+			}
+		}
+
+		if len(numberedTags) != 0 {
+			numbered[word] = numberedTags
+		}
+	}
+
+	return ExternalLexiconHandler{
+		lexicon:  numbered,
+		model:    m,
+		uf:       m.UnigramFreqs(),
+		fallback: fallback,
+		mode:     mode,
+		weight:   weight,
+	}
+}
+
+// TagProbs returns P(w|t) for a particular word 'w'.
+func (h ExternalLexiconHandler) TagProbs(word string) map[model.Tag]float64 {
+	probs := h.externalTagProbs(word)
+	if len(probs) == 0 {
+		if h.fallback == nil {
+			return make(map[model.Tag]float64)
+		}
+
+		return h.fallback.TagProbs(word)
+	}
+
+	if h.mode == ExternalLexiconAugment && h.fallback != nil {
+		return interpolateTagProbs(
+			[]map[model.Tag]float64{probs, h.fallback.TagProbs(word)},
+			[]float64{h.weight, 1 - h.weight})
+	}
+
+	return probs
+}
+
+// externalTagProbs returns the emission probabilities of a word according
+// to the external lexicon.
+func (h ExternalLexiconHandler) externalTagProbs(word string) map[model.Tag]float64 {
+	tags, ok := h.lexicon[word]
+	if !ok {
+		// Try the lowercase variant of capitalized words.
+		tags, ok = h.lexicon[strings.ToLower(word)]
+		if !ok {
+			return nil
+		}
+	}
+
+	first, _ := utf8.DecodeRuneInString(word)
+	capital := unicode.IsUpper(first)
+
+	// Distribute the probability mass using the lexicon frequencies if
+	// available, otherwise using the unigram frequencies. Tags without a
+	// frequency are floored to frequency 1, so that they keep some mass.
+	var haveFreqs bool
+	for _, freq := range tags {
+		if freq != 0 {
+			haveFreqs = true
+			break
+		}
+	}
+
+	tp := make(map[model.Tag]float64)
+	var total float64
+	for number, freq := range tags {
+		tag, ok := capitalVariant(h.model, model.Tag{Tag: number}, capital)
+		if !ok {
+			continue
+		}
+
+		mass := math.Max(float64(freq), 1)
+		if !haveFreqs {
+			mass = float64(h.uf[model.Unigram{T1: tag}])
+		}
+
+		tp[tag] = mass
+		total += mass
+	}
+
+	if total == 0 {
+		return nil
+	}
+
+	for tag, mass := range tp {
+		tp[tag] = mass / total
+	}
+
+	// P(w|t) is proportional to P(t|w) / P(t).
+	bayesianInversion(h.uf, tp)
+
+	for tag, prob := range tp {
+		tp[tag] = math.Log(prob)
+	}
+
+	return tp
+}
@@ -0,0 +1,81 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package words
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/danieldk/citar/model"
+)
+
+var _ WordHandler = HyphenHandler{}
+
+// HyphenHandler is an emission probability estimator for unknown words
+// that contain a hyphen. The last component of a hyphenated word is
+// usually its head (e.g. Krise in Euro-Krise). If the last component is
+// a known word, the tag distribution of the component is used to
+// estimate the emission probabilities of the word. Otherwise, the
+// fallback handler (typically a SuffixHandler, which uses a separate
+// distribution for hyphenated words) is used.
+type HyphenHandler struct {
+	model        model.Model
+	wordTagFreqs map[string]map[model.Tag]int
+	uf           map[model.Unigram]int
+	skip         map[uint]interface{}
+	fallback     WordHandler
+}
+
+// NewHyphenHandler constructs a new HyphenHandler from the given model
+// and fallback handler.
+func NewHyphenHandler(m model.Model, fallback WordHandler) HyphenHandler {
+	return HyphenHandler{
+		model:        m,
+		wordTagFreqs: m.WordTagFreqs(),
+		uf:           m.UnigramFreqs(),
+		skip:         unknownWordSkipTags(m),
+		fallback:     fallback,
+	}
+}
+
+// TagProbs estimates P(w|t) for a particular word 'w'.
+func (h HyphenHandler) TagProbs(word string) map[model.Tag]float64 {
+	if head, ok := h.Head(word); ok {
+		if tp := headTagProbs(h.model, h.skip, word, head); len(tp) != 0 {
+			bayesianInversion(h.uf, tp)
+			return bestNLogSpace(tp, len(tp))
+		}
+	}
+
+	return h.fallback.TagProbs(word)
+}
+
+// Head returns the form of the last component of a hyphenated word that
+// is in the lexicon. The component is looked up using the same rules as
+// Lexicon: first as-is, then in lowercase if it starts with an uppercase
+// letter. The last return value is false if the word is not hyphenated
+// or if the last component is not a known word.
+func (h HyphenHandler) Head(word string) (string, bool) {
+	idx := strings.LastIndex(word, "-")
+	if idx == -1 || idx == len(word)-1 {
+		return "", false
+	}
+
+	head := word[idx+1:]
+	if _, ok := h.wordTagFreqs[head]; ok {
+		return head, true
+	}
+
+	first, _ := utf8.DecodeRuneInString(head)
+	if unicode.IsUpper(first) {
+		lower := strings.ToLower(head)
+		if _, ok := h.wordTagFreqs[lower]; ok {
+			return lower, true
+		}
+	}
+
+	return "", false
+}
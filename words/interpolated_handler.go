@@ -0,0 +1,171 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package words
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/danieldk/citar/model"
+)
+
+// InterpolationMode determines how an InterpolatedHandler combines the
+// distributions of its components.
+type InterpolationMode int
+
+const (
+	// LinearInterpolation computes a weighted sum of the probabilities.
+	LinearInterpolation InterpolationMode = iota
+
+	// LogLinearInterpolation computes a weighted sum of the log
+	// probabilities.
+	LogLinearInterpolation
+)
+
+// ParseInterpolationMode parses the name of an interpolation mode
+// ("linear" or "log_linear").
+func ParseInterpolationMode(mode string) (InterpolationMode, error) {
+	switch mode {
+	case "linear":
+		return LinearInterpolation, nil
+	case "log_linear":
+		return LogLinearInterpolation, nil
+	}
+
+	return 0, fmt.Errorf("unknown interpolation mode: %s", mode)
+}
+
+// InterpolatedComponent is a component of an InterpolatedHandler. The
+// component has the weight Weight, unless the word belongs to a word
+// class that has a weight in ClassWeights.
+type InterpolatedComponent struct {
+	Handler      WordHandler
+	Weight       float64
+	ClassWeights map[string]float64
+}
+
+var _ WordHandler = InterpolatedHandler{}
+
+// InterpolatedHandler estimates emission probabilities by interpolating
+// the distributions of several word handlers. In linear interpolation:
+//
+// P(w|t) = Σ_i λ_i P_i(w|t)
+//
+// In log-linear interpolation:
+//
+// log P(w|t) = Σ_i λ_i log P_i(w|t)
+//
+// where a tag that is not returned by a component receives the lowest log
+// probability of that component for the word. Components that do not
+// return any tags for a word are ignored and the weights of the remaining
+// components are normalized to sum to one.
+type InterpolatedHandler struct {
+	components []InterpolatedComponent
+	classifier *WordClassifier
+	mode       InterpolationMode
+}
+
+// NewInterpolatedHandler constructs an InterpolatedHandler. The word
+// classifier is used to select class-specific weights, it can be nil when
+// no component has class weights.
+func NewInterpolatedHandler(components []InterpolatedComponent, classifier *WordClassifier,
+	mode InterpolationMode) InterpolatedHandler {
+	return InterpolatedHandler{
+		components: components,
+		classifier: classifier,
+		mode:       mode,
+	}
+}
+
+// TagProbs estimates P(w|t) for a particular word 'w'.
+func (h InterpolatedHandler) TagProbs(word string) map[model.Tag]float64 {
+	var class string
+	if h.classifier != nil {
+		class = h.classifier.WordClass(word)
+	}
+
+	var dists []map[model.Tag]float64
+	var weights []float64
+	var weightSum float64
+	for _, component := range h.components {
+		weight := component.Weight
+		if classWeight, ok := component.ClassWeights[class]; ok {
+			weight = classWeight
+		}
+
+		if weight == 0 {
+			continue
+		}
+
+		dist := component.Handler.TagProbs(word)
+		if len(dist) == 0 {
+			continue
+		}
+
+		dists = append(dists, dist)
+		weights = append(weights, weight)
+		weightSum += weight
+	}
+
+	probs := make(map[model.Tag]float64)
+	if len(dists) == 0 {
+		return probs
+	}
+
+	if h.mode == LogLinearInterpolation {
+		for _, dist := range dists {
+			for tag := range dist {
+				probs[tag] = 0
+			}
+		}
+
+		for i, dist := range dists {
+			floor := math.Inf(1)
+			for _, logProb := range dist {
+				floor = math.Min(floor, logProb)
+			}
+
+			for tag := range probs {
+				logProb, ok := dist[tag]
+				if !ok {
+					logProb = floor
+				}
+
+				probs[tag] += weights[i] / weightSum * logProb
+			}
+		}
+
+		return probs
+	}
+
+	for i := range weights {
+		weights[i] /= weightSum
+	}
+
+	return interpolateTagProbs(dists, weights)
+}
+
+// interpolateTagProbs linearly interpolates distributions of log
+// probabilities using the given weights. The result is a distribution of
+// log probabilities, tags that receive no probability mass are removed.
+func interpolateTagProbs(dists []map[model.Tag]float64, weights []float64) map[model.Tag]float64 {
+	probs := make(map[model.Tag]float64)
+	for i, dist := range dists {
+		for tag, logProb := range dist {
+			probs[tag] += weights[i] * math.Exp(logProb)
+		}
+	}
+
+	for tag, prob := range probs {
+		if prob == 0 {
+			delete(probs, tag)
+			continue
+		}
+
+		probs[tag] = math.Log(prob)
+	}
+
+	return probs
+}
@@ -5,6 +5,7 @@
 package words
 
 import (
+	"fmt"
 	"math"
 	"regexp"
 	"strings"
@@ -22,6 +23,61 @@ type wordTagProbs map[string]map[model.Tag]float64
 type Lexicon struct {
 	wordTagProbs wordTagProbs
 	fallback     WordHandler
+	smoothing    *lexiconSmoothing
+}
+
+type lexiconSmoothing struct {
+	config    LexiconSmoothingConfig
+	smoother  WordHandler
+	uf        map[model.Unigram]int
+	wordFreqs map[string]int
+}
+
+// LexiconSmoothingConfig stores the configuration of known-word smoothing.
+// The tag distribution of words with a training frequency up to MaxFreq is
+// interpolated with the distribution of a smoothing word handler (such as
+// a SuffixHandler), where Weight is the weight of the smoothing handler.
+// Smoothing is disabled when MaxFreq is zero.
+//
+// MaxFreq and Weight determine which words are smoothed and how much
+// probability mass the smoothing handler receives. MaxTags determines
+// which tags receive this mass: if MaxTags is zero, the word is
+// interpolated with the full distribution of the smoothing handler, so
+// that every tag that the handler proposes becomes a candidate. Otherwise,
+// the candidate tags of the word are only extended with the MaxTags most
+// probable tags of the smoothing handler, which share the probability
+// mass Weight.
+type LexiconSmoothingConfig struct {
+	MaxFreq int     `toml:"max_freq"`
+	MaxTags int     `toml:"max_tags"`
+	Weight  float64 `toml:"weight"`
+}
+
+// DefaultLexiconSmoothingConfig returns a LexiconSmoothingConfig with
+// smoothing disabled.
+func DefaultLexiconSmoothingConfig() LexiconSmoothingConfig {
+	return LexiconSmoothingConfig{
+		MaxFreq: 0,
+		MaxTags: 0,
+		Weight:  0.1,
+	}
+}
+
+// Validate checks that the configuration is valid.
+func (c LexiconSmoothingConfig) Validate() error {
+	if c.MaxFreq < 0 {
+		return fmt.Errorf("maximum frequency should not be negative: %d", c.MaxFreq)
+	}
+
+	if c.MaxTags < 0 {
+		return fmt.Errorf("maximum number of tags should not be negative: %d", c.MaxTags)
+	}
+
+	if c.Weight < 0 || c.Weight > 1 {
+		return fmt.Errorf("weight should be in [0, 1]: %f", c.Weight)
+	}
+
+	return nil
 }
 
 // NewLexicon constructs a new Lexicon from word/tag frequencies and unigram
@@ -45,22 +101,59 @@ func NewLexiconWithFallback(wtf map[string]map[model.Tag]int, uf map[model.Unigr
 	}
 }
 
+// NewSmoothedLexicon constructs a new Lexicon from word/tag frequencies,
+// unigram frequencies, a fallback, and a smoothing word handler. The
+// emission probabilities of low-frequency words are smoothed using the
+// tag distribution of the smoothing handler, as specified by the smoothing
+// configuration. This allows a word that was seen infrequently to receive
+// tags that it did not occur with in the training data. The fallback is
+// optional and can be nil.
+func NewSmoothedLexicon(wtf map[string]map[model.Tag]int, uf map[model.Unigram]int,
+	fallback WordHandler, smoother WordHandler, config LexiconSmoothingConfig) Lexicon {
+	lexicon := Lexicon{
+		wordTagProbs: calculateWordTagProbs(wtf, uf),
+		fallback:     fallback,
+	}
+
+	if config.MaxFreq == 0 {
+		return lexicon
+	}
+
+	wordFreqs := make(map[string]int)
+	for word, counts := range wtf {
+		for _, freq := range counts {
+			wordFreqs[word] += freq
+		}
+	}
+
+	lexicon.smoothing = &lexiconSmoothing{
+		config:    config,
+		smoother:  smoother,
+		uf:        uf,
+		wordFreqs: wordFreqs,
+	}
+
+	return lexicon
+}
+
 // TagProbs returns P(w|t) for a particular word 'w'. Probabilities are only
 // returned for tags with which the word occurred in the training data, except
-// if the word did not occur in the training data and a fallback is used.
+// if the word did not occur in the training data and a fallback is used, or
+// if the word is smoothed.
 func (l Lexicon) TagProbs(word string) map[model.Tag]float64 {
 	// Lookup word. If it is known, return P(w|t) for each tag that
 	// the word was seen with in the training model.
 	if probs, ok := l.wordTagProbs[word]; ok {
-		return probs
+		return l.smooth(word, probs)
 	}
 
 	// If the word could not be found, maybe its lowercase variant can
 	// be found (e.g. capitalized words that start a sentence).
 	runes := []rune(word)
 	if unicode.IsUpper(runes[0]) {
-		if probs, ok := l.wordTagProbs[strings.ToLower(word)]; ok {
-			return probs
+		lower := strings.ToLower(word)
+		if probs, ok := l.wordTagProbs[lower]; ok {
+			return l.smooth(lower, probs)
 		}
 	}
 
@@ -72,6 +165,69 @@ func (l Lexicon) TagProbs(word string) map[model.Tag]float64 {
 	return make(map[model.Tag]float64)
 }
 
+// smooth interpolates the tag distribution of a low-frequency word with the
+// distribution of the smoothing handler. The interpolation is carried out
+// on P(t|w), since the smoothing handler only estimates P(w|t) up to a
+// word-specific constant:
+//
+// P(t|w) = (1 - λ) f(w,t) / f(w) + λ P_smoother(t|w)
+//
+// The result is converted back to P(w|t) = P(t|w) f(w) / f(t).
+func (l Lexicon) smooth(word string, probs map[model.Tag]float64) map[model.Tag]float64 {
+	s := l.smoothing
+	if s == nil {
+		return probs
+	}
+
+	wordFreq := float64(s.wordFreqs[word])
+	if wordFreq == 0 || wordFreq > float64(s.config.MaxFreq) {
+		return probs
+	}
+
+	smoothed := make(map[model.Tag]float64)
+	for tag, logProb := range s.smoother.TagProbs(word) {
+		p := math.Exp(logProb) * float64(s.uf[model.Unigram{T1: tag}])
+		if p != 0 {
+			smoothed[tag] = p
+		}
+	}
+
+	if s.config.MaxTags != 0 {
+		smoothed = bestN(smoothed, s.config.MaxTags)
+	}
+
+	var norm float64
+	for _, p := range smoothed {
+		norm += p
+	}
+
+	if norm == 0 {
+		return probs
+	}
+
+	tagProbs := make(map[model.Tag]float64)
+	for tag, logProb := range probs {
+		// f(w,t) / f(w) = P(w|t) f(t) / f(w)
+		tagFreq := float64(s.uf[model.Unigram{T1: tag}])
+		tagProbs[tag] = (1 - s.config.Weight) * math.Exp(logProb) * tagFreq / wordFreq
+	}
+
+	for tag, p := range smoothed {
+		tagProbs[tag] += s.config.Weight * p / norm
+	}
+
+	result := make(map[model.Tag]float64)
+	for tag, p := range tagProbs {
+		if p == 0 {
+			continue
+		}
+
+		result[tag] = math.Log(p * wordFreq / float64(s.uf[model.Unigram{T1: tag}]))
+	}
+
+	return result
+}
+
 func calculateWordTagProbs(wtf map[string]map[model.Tag]int, uf map[model.Unigram]int) wordTagProbs {
 	probs := make(wordTagProbs)
 
@@ -90,9 +246,14 @@ func calculateWordTagProbs(wtf map[string]map[model.Tag]int, uf map[model.Unigra
 	return probs
 }
 
+// Substitution is a rewrite rule that is applied to words that are not in
+// the lexicon. All matches of Pattern are replaced by Replacement. If
+// Lookup is true, the lexicon is consulted after the application of the
+// rule. The lexicon is always consulted after the last rule.
 type Substitution struct {
 	Pattern     *regexp.Regexp
 	Replacement string
+	Lookup      bool
 }
 
 type SubstLexicon struct {
@@ -133,15 +294,25 @@ func (l SubstLexicon) TagProbs(word string) map[model.Tag]float64 {
 		return probs
 	}
 
-	substWord := word
-	// Attempt substitutions
-	for _, subst := range l.substitutions {
+	// Attempt substitutions, looking up the substituted word after rules
+	// that request a lookup and after the last rule.
+	substWord, lookedUp := word, word
+	for i, subst := range l.substitutions {
 		substWord = subst.Pattern.ReplaceAllString(substWord, subst.Replacement)
-	}
+		if !subst.Lookup && i != len(l.substitutions)-1 {
+			continue
+		}
 
-	probs = l.lexicon.TagProbs(substWord)
-	if len(probs) != 0 {
-		return probs
+		// Only look up words that were not looked up before.
+		if substWord == lookedUp || len(substWord) == 0 {
+			continue
+		}
+		lookedUp = substWord
+
+		probs = l.lexicon.TagProbs(substWord)
+		if len(probs) != 0 {
+			return probs
+		}
 	}
 
 	// Try the fallback word handler, if it is available.
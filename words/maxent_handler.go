@@ -0,0 +1,363 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package words
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"unicode"
+
+	"github.com/danieldk/citar/model"
+)
+
+var _ WordHandler = MaxEntHandler{}
+
+// MaxEntHandler is an emission probability estimator for unknown words
+// that uses a log-linear (maximum entropy) classifier. The classifier
+// estimates P(t|w) using features of the word: its shape, prefixes and
+// suffixes, and the classes of characters in the word. It is trained
+// on the low-frequency words of the training data. The emission
+// probabilities P(w|t) are obtained using Bayesian inversion.
+type MaxEntHandler struct {
+	features    map[string]int
+	weights     [][]float64
+	tags        []model.Tag
+	uf          map[model.Unigram]int
+	maxAffixLen int
+	maxTags     int
+}
+
+// MaxEntHandlerConfig stores the configuration of a MaxEntHandler.
+// Words with a frequency up to MaxFreq are used as training data. The
+// classifier is trained using Iterations passes of stochastic gradient
+// descent with the given initial learning rate and L2 regularization.
+// MaxAffixLen is the maximum length of prefix and suffix features.
+type MaxEntHandlerConfig struct {
+	MaxFreq      int     `toml:"max_freq"`
+	MaxAffixLen  int     `toml:"max_affix_len"`
+	MaxTags      int     `toml:"max_tags"`
+	Iterations   int     `toml:"iterations"`
+	LearningRate float64 `toml:"learning_rate"`
+	L2           float64 `toml:"l2"`
+}
+
+// DefaultMaxEntHandlerConfig returns a MaxEntHandlerConfig with reasonable
+// defaults.
+func DefaultMaxEntHandlerConfig() MaxEntHandlerConfig {
+	return MaxEntHandlerConfig{
+		MaxFreq:      8,
+		MaxAffixLen:  4,
+		MaxTags:      10,
+		Iterations:   20,
+		LearningRate: 0.1,
+		L2:           1e-4,
+	}
+}
+
+// Validate checks that the configuration is valid.
+func (c MaxEntHandlerConfig) Validate() error {
+	if c.MaxFreq < 1 {
+		return fmt.Errorf("maximum frequency should be at least 1: %d", c.MaxFreq)
+	}
+
+	if c.MaxAffixLen < 0 {
+		return fmt.Errorf("maximum affix length should not be negative: %d", c.MaxAffixLen)
+	}
+
+	if c.MaxTags < 1 {
+		return fmt.Errorf("maximum number of tags should be at least 1: %d", c.MaxTags)
+	}
+
+	if c.Iterations < 1 {
+		return fmt.Errorf("number of iterations should be at least 1: %d", c.Iterations)
+	}
+
+	if c.LearningRate <= 0 {
+		return fmt.Errorf("learning rate should be positive: %f", c.LearningRate)
+	}
+
+	if c.L2 < 0 {
+		return fmt.Errorf("L2 regularization should not be negative: %f", c.L2)
+	}
+
+	return nil
+}
+
+// NewMaxEntHandler trains a MaxEntHandler on the low-frequency words
+// in the model.
+func NewMaxEntHandler(config MaxEntHandlerConfig, m model.Model) MaxEntHandler {
+	skip := unknownWordSkipTags(m)
+
+	h := MaxEntHandler{
+		features:    make(map[string]int),
+		uf:          m.UnigramFreqs(),
+		maxAffixLen: config.MaxAffixLen,
+		maxTags:     config.MaxTags,
+	}
+
+	tagIndices := make(map[model.Tag]int)
+	for unigram := range m.UnigramFreqs() {
+		if _, ok := skip[unigram.T1.Tag]; !ok {
+			h.tags = append(h.tags, unigram.T1)
+		}
+	}
+	sort.Slice(h.tags, func(i, j int) bool {
+		if h.tags[i].Tag != h.tags[j].Tag {
+			return h.tags[i].Tag < h.tags[j].Tag
+		}
+		return !h.tags[i].Capital && h.tags[j].Capital
+	})
+	for i, tag := range h.tags {
+		tagIndices[tag] = i
+	}
+
+	// Collect training instances in a fixed order, so that training is
+	// deterministic.
+	var trainWords []string
+	for word, tagFreqs := range m.WordTagFreqs() {
+		if word == model.StartToken || word == model.EndToken || len(word) == 0 {
+			continue
+		}
+
+		var wordFreq int
+		for _, freq := range tagFreqs {
+			wordFreq += freq
+		}
+
+		if wordFreq <= config.MaxFreq {
+			trainWords = append(trainWords, word)
+		}
+	}
+	sort.Strings(trainWords)
+
+	instances := make([]maxEntInstance, 0, len(trainWords))
+	for _, word := range trainWords {
+		instance := maxEntInstance{
+			features: h.indexFeatures(wordFeatures(word, config.MaxAffixLen)),
+			tagFreqs: make(map[int]float64),
+		}
+
+		for tag, freq := range m.WordTagFreqs()[word] {
+			if idx, ok := tagIndices[tag]; ok {
+				instance.tagFreqs[idx] += float64(freq)
+			}
+		}
+
+		if len(instance.tagFreqs) != 0 {
+			instances = append(instances, instance)
+		}
+	}
+
+	h.weights = make([][]float64, len(h.features))
+	for i := range h.weights {
+		h.weights[i] = make([]float64, len(h.tags))
+	}
+
+	h.train(config, instances)
+
+	return h
+}
+
+type maxEntInstance struct {
+	features []int
+	tagFreqs map[int]float64
+}
+
+func (h *MaxEntHandler) indexFeatures(features []string) []int {
+	indices := make([]int, 0, len(features))
+	for _, feature := range features {
+		idx, ok := h.features[feature]
+		if !ok {
+			idx = len(h.features)
+			h.features[feature] = idx
+		}
+		indices = append(indices, idx)
+	}
+
+	return indices
+}
+
+// train estimates the feature weights using stochastic gradient descent.
+// The tag frequencies of a word are used as soft targets.
+func (h MaxEntHandler) train(config MaxEntHandlerConfig, instances []maxEntInstance) {
+	for iter := 0; iter < config.Iterations; iter++ {
+		rate := config.LearningRate / (1 + float64(iter))
+
+		for _, instance := range instances {
+			probs := h.classProbs(instance.features)
+
+			var total float64
+			for _, freq := range instance.tagFreqs {
+				total += freq
+			}
+
+			// Gradient of the log-likelihood: observed minus expected
+			// feature counts.
+			for i := range probs {
+				grad := -total * probs[i]
+				if freq, ok := instance.tagFreqs[i]; ok {
+					grad += freq
+				}
+
+				for _, f := range instance.features {
+					w := h.weights[f]
+					w[i] += rate * (grad - config.L2*w[i])
+				}
+			}
+		}
+	}
+}
+
+// classProbs returns P(t|w) for all tags, given the features of a word.
+func (h MaxEntHandler) classProbs(features []int) []float64 {
+	scores := make([]float64, len(h.tags))
+	for _, f := range features {
+		for i, w := range h.weights[f] {
+			scores[i] += w
+		}
+	}
+
+	maxScore := math.Inf(-1)
+	for _, score := range scores {
+		maxScore = math.Max(maxScore, score)
+	}
+
+	var norm float64
+	for i, score := range scores {
+		scores[i] = math.Exp(score - maxScore)
+		norm += scores[i]
+	}
+
+	for i := range scores {
+		scores[i] /= norm
+	}
+
+	return scores
+}
+
+// TagProbs estimates P(w|t) for a particular word 'w'.
+func (h MaxEntHandler) TagProbs(word string) map[model.Tag]float64 {
+	var features []int
+	for _, feature := range wordFeatures(word, h.maxAffixLen) {
+		if idx, ok := h.features[feature]; ok {
+			features = append(features, idx)
+		}
+	}
+
+	tp := make(map[model.Tag]float64)
+	for i, prob := range h.classProbs(features) {
+		tp[h.tags[i]] = prob
+	}
+
+	bayesianInversion(h.uf, tp)
+
+	return bestNLogSpace(tp, h.maxTags)
+}
+
+var urlPattern = regexp.MustCompile(`^(https?://|www\.)`)
+var emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[a-zA-Z]+$`)
+
+// wordFeatures extracts the features of a word that are used by the
+// MaxEntHandler.
+func wordFeatures(word string, maxAffixLen int) []string {
+	features := []string{"bias", "shape=" + wordShape(word)}
+
+	runes := []rune(word)
+	for i := 1; i <= maxAffixLen && i <= len(runes); i++ {
+		features = append(features,
+			"prefix="+string(runes[:i]),
+			"suffix="+string(runes[len(runes)-i:]))
+	}
+
+	var upper, lower, digit, internalUpper, hyphen, apostrophe, punct bool
+	for i, r := range runes {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+			if i > 0 {
+				internalUpper = true
+			}
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case r == '-':
+			hyphen = true
+		case r == '\'' || r == '’':
+			apostrophe = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			punct = true
+		}
+	}
+
+	if len(runes) > 0 && unicode.IsUpper(runes[0]) {
+		features = append(features, "init_upper")
+	}
+	if upper && !lower {
+		features = append(features, "all_upper")
+	}
+	if internalUpper {
+		features = append(features, "internal_upper")
+	}
+	if digit {
+		features = append(features, "has_digit")
+	}
+	if digit && (upper || lower) {
+		features = append(features, "digit_letter")
+	}
+	if hyphen {
+		features = append(features, "has_hyphen")
+	}
+	if apostrophe {
+		features = append(features, "has_apostrophe")
+	}
+	if punct {
+		features = append(features, "has_punct")
+	}
+	if urlPattern.MatchString(word) {
+		features = append(features, "url")
+	}
+	if emailPattern.MatchString(word) {
+		features = append(features, "email")
+	}
+
+	length := len(runes)
+	if length > 10 {
+		length = 10
+	}
+	features = append(features, "length="+strconv.Itoa(length))
+
+	return features
+}
+
+// wordShape returns the shape of a word: uppercase letters are replaced
+// by 'X', lowercase letters by 'x', and digits by 'd'. Other characters
+// are retained. Repetitions of the same character are collapsed.
+func wordShape(word string) string {
+	var shape bytes.Buffer
+	var last rune
+
+	for _, r := range word {
+		switch {
+		case unicode.IsUpper(r):
+			r = 'X'
+		case unicode.IsLower(r):
+			r = 'x'
+		case unicode.IsDigit(r):
+			r = 'd'
+		}
+
+		if r != last {
+			shape.WriteRune(r)
+			last = r
+		}
+	}
+
+	return shape.String()
+}
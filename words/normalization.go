@@ -0,0 +1,61 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package words
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/danieldk/citar/model"
+)
+
+var _ WordHandler = NormalizingHandler{}
+
+// NormalizingHandler normalizes words before passing them to another word
+// handler. It should be used with the normalization that was applied to
+// the training data (see model.Model.Normalization).
+//
+// When the normalization folds case, the capitalization of a word is
+// lost. In that case, the handler only returns the tags with the same
+// capitalization as the original word, unless there are no such tags.
+type NormalizingHandler struct {
+	handler       WordHandler
+	model         model.Model
+	normalization model.Normalization
+}
+
+// NewNormalizingHandler constructs a NormalizingHandler from a word
+// handler and the normalization of a model.
+func NewNormalizingHandler(handler WordHandler, m model.Model) NormalizingHandler {
+	return NormalizingHandler{
+		handler:       handler,
+		model:         m,
+		normalization: m.Normalization(),
+	}
+}
+
+// TagProbs returns P(w|t) for a particular word 'w'.
+func (h NormalizingHandler) TagProbs(word string) map[model.Tag]float64 {
+	probs := h.handler.TagProbs(h.normalization.Normalize(word))
+	if !h.normalization.CaseFold {
+		return probs
+	}
+
+	first, _ := utf8.DecodeRuneInString(word)
+	capital := unicode.IsUpper(first)
+
+	filtered := make(map[model.Tag]float64)
+	for tag, prob := range probs {
+		if h.model.IsCapitalTag(tag) == capital {
+			filtered[tag] = prob
+		}
+	}
+
+	if len(filtered) == 0 {
+		return probs
+	}
+
+	return filtered
+}
@@ -0,0 +1,83 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package words
+
+import "github.com/danieldk/citar/model"
+
+var _ WordHandler = OpenClassHandler{}
+
+// OpenClassHandler ensures that unknown words are never assigned
+// closed-class tags. It removes the closed-class tags (and the sentence
+// boundary tags) from the distribution of another word handler. If the
+// handler does not return any open-class tag for a word, all open-class
+// tags are considered to be equally likely, so that the tag is determined
+// by the context of the word.
+type OpenClassHandler struct {
+	handler WordHandler
+	skip    map[uint]interface{}
+	uniform map[model.Tag]float64
+}
+
+// NewOpenClassHandler constructs an OpenClassHandler that wraps the given
+// handler. The closed-class tags are read from the model.
+func NewOpenClassHandler(handler WordHandler, m model.Model) OpenClassHandler {
+	skip := unknownWordSkipTags(m)
+
+	// With P(t|w) = P(t), Bayesian inversion gives the same emission
+	// probability for every tag.
+	var total int
+	for unigram, freq := range m.UnigramFreqs() {
+		if _, ok := skip[unigram.T1.Tag]; !ok {
+			total += freq
+		}
+	}
+
+	uniform := make(map[model.Tag]float64)
+	for unigram := range m.UnigramFreqs() {
+		if _, ok := skip[unigram.T1.Tag]; !ok {
+			uniform[unigram.T1] = 1 / float64(total)
+		}
+	}
+
+	return OpenClassHandler{
+		handler: handler,
+		skip:    skip,
+		uniform: bestNLogSpace(uniform, len(uniform)),
+	}
+}
+
+// TagProbs returns P(w|t) for a particular word 'w'.
+func (h OpenClassHandler) TagProbs(word string) map[model.Tag]float64 {
+	probs := h.handler.TagProbs(word)
+
+	var closed bool
+	for tag := range probs {
+		if _, ok := h.skip[tag.Tag]; ok {
+			closed = true
+			break
+		}
+	}
+
+	if !closed {
+		if len(probs) == 0 {
+			return copyTagProbs(h.uniform)
+		}
+
+		return probs
+	}
+
+	filtered := make(map[model.Tag]float64)
+	for tag, logProb := range probs {
+		if _, ok := h.skip[tag.Tag]; !ok {
+			filtered[tag] = logProb
+		}
+	}
+
+	if len(filtered) == 0 {
+		return copyTagProbs(h.uniform)
+	}
+
+	return filtered
+}
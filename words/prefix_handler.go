@@ -0,0 +1,140 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package words
+
+import (
+	"fmt"
+
+	"github.com/danieldk/citar/model"
+)
+
+var _ WordHandler = PrefixHandler{}
+
+// PrefixHandler is an emission probability estimator that uses word
+// prefixes. It is the counterpart of SuffixHandler for languages that
+// mark word classes using prefixes, such as Bantu languages or German
+// participles (ge-). Like SuffixHandler, it uses a separate distribution
+// for each word class.
+type PrefixHandler struct {
+	classes []wordClass
+	trees   []*wordSuffixTree
+	maxTags int
+}
+
+// PrefixHandlerConfig stores the configuration for a PrefixHandler. The
+// parameters have the same meaning as their SuffixHandlerConfig
+// counterparts.
+type PrefixHandlerConfig struct {
+	MaxPrefixLen    int               `toml:"max_prefix_len"`
+	UpperMaxFreq    int               `toml:"upper_max_freq"`
+	LowerMaxFreq    int               `toml:"lower_max_freq"`
+	DashMaxFreq     int               `toml:"dash_max_freq"`
+	CardinalMaxFreq int               `toml:"cardinal_max_freq"`
+	MaxTags         int               `toml:"max_tags"`
+	Theta           float64           `toml:"theta"`
+	WordClasses     []WordClassConfig `toml:"word_class"`
+}
+
+// DefaultPrefixHandlerConfig returns a PrefixHandlerConfig with the same
+// frequency cutoffs as DefaultSuffixHandlerConfig.
+func DefaultPrefixHandlerConfig() PrefixHandlerConfig {
+	return PrefixHandlerConfig{
+		MaxPrefixLen:    3,
+		UpperMaxFreq:    2,
+		LowerMaxFreq:    8,
+		DashMaxFreq:     4,
+		MaxTags:         10,
+		CardinalMaxFreq: 10,
+	}
+}
+
+// suffixConfig returns the equivalent SuffixHandlerConfig, which is used
+// for validation and to obtain the word classes.
+func (c PrefixHandlerConfig) suffixConfig() SuffixHandlerConfig {
+	return SuffixHandlerConfig{
+		MaxSuffixLen:    c.MaxPrefixLen,
+		UpperMaxFreq:    c.UpperMaxFreq,
+		LowerMaxFreq:    c.LowerMaxFreq,
+		DashMaxFreq:     c.DashMaxFreq,
+		CardinalMaxFreq: c.CardinalMaxFreq,
+		MaxTags:         c.MaxTags,
+		Theta:           c.Theta,
+		WordClasses:     c.WordClasses,
+	}
+}
+
+// Validate checks that the configuration is valid.
+func (c PrefixHandlerConfig) Validate() error {
+	return c.suffixConfig().Validate()
+}
+
+// NewPrefixHandler constructs a new PrefixHandler from the given
+// configuration and model. NewPrefixHandler panics when the configuration
+// is invalid, use PrefixHandlerConfig.Validate to check the configuration.
+func NewPrefixHandler(config PrefixHandlerConfig, m model.Model) PrefixHandler {
+	classes, err := compileWordClasses(config.suffixConfig().wordClasses())
+	if err != nil {
+		panic(fmt.Sprintf("invalid prefix handler configuration: %s", err))
+	}
+
+	skip := unknownWordSkipTags(m)
+
+	theta := config.Theta
+	if theta == 0 {
+		theta = calcTheta(m.UnigramFreqs(), skip)
+	}
+
+	return PrefixHandler{
+		classes: classes,
+		trees:   newClassTrees(m, classes, skip, theta, config.MaxPrefixLen, true),
+		maxTags: config.MaxTags,
+	}
+}
+
+// TagProbs estimates P(w|t) for a particular word 'w'.
+func (h PrefixHandler) TagProbs(word string) map[model.Tag]float64 {
+	t := h.trees[selectWordClass(h.classes, word)]
+
+	return bestNLogSpace(t.suffixTagProbs(word), h.maxTags)
+}
+
+// WordClass returns the name of the word class that is used to estimate
+// the emission probabilities of a word.
+func (h PrefixHandler) WordClass(word string) string {
+	return h.classes[selectWordClass(h.classes, word)].name
+}
+
+var _ WordHandler = PrefixSuffixHandler{}
+
+// PrefixSuffixHandler estimates emission probabilities by linear
+// interpolation of the estimates of a prefix handler and a suffix handler:
+//
+// P(w|t) = λ P_prefix(w|t) + (1 - λ) P_suffix(w|t)
+//
+// where λ is the prefix weight.
+type PrefixSuffixHandler struct {
+	prefixHandler WordHandler
+	suffixHandler WordHandler
+	prefixWeight  float64
+}
+
+// NewPrefixSuffixHandler constructs a PrefixSuffixHandler from a prefix
+// handler, a suffix handler, and the weight of the prefix handler (in
+// [0, 1]).
+func NewPrefixSuffixHandler(prefixHandler, suffixHandler WordHandler,
+	prefixWeight float64) PrefixSuffixHandler {
+	return PrefixSuffixHandler{
+		prefixHandler: prefixHandler,
+		suffixHandler: suffixHandler,
+		prefixWeight:  prefixWeight,
+	}
+}
+
+// TagProbs estimates P(w|t) for a particular word 'w'.
+func (h PrefixSuffixHandler) TagProbs(word string) map[model.Tag]float64 {
+	return interpolateTagProbs(
+		[]map[model.Tag]float64{h.prefixHandler.TagProbs(word), h.suffixHandler.TagProbs(word)},
+		[]float64{h.prefixWeight, 1 - h.prefixWeight})
+}
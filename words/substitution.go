@@ -0,0 +1,115 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package words
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/danieldk/citar/model"
+)
+
+// ReadSubstitutions reads substitution rules. Each rule is on a separate
+// line and consists of a pattern (a regular expression), a replacement,
+// and optional flags, separated by tabs. The flags are a comma-separated
+// list of:
+//
+// i: the pattern is case-insensitive
+// lookup: look up the word in the lexicon after applying the rule
+//
+// The rules are applied in the order in which they are specified. Empty
+// lines and lines starting with '#' are ignored.
+func ReadSubstitutions(reader io.Reader) ([]Substitution, error) {
+	var substs []Substitution
+
+	scanner := bufio.NewScanner(reader)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		parts := strings.Split(line, "\t")
+		if len(parts) != 2 && len(parts) != 3 {
+			return nil, fmt.Errorf("line %d: expected pattern, replacement, and optional flags", lineno)
+		}
+
+		var caseInsensitive, lookup bool
+		if len(parts) == 3 {
+			for _, flag := range strings.Split(parts[2], ",") {
+				switch strings.TrimSpace(flag) {
+				case "i":
+					caseInsensitive = true
+				case "lookup":
+					lookup = true
+				case "":
+				default:
+					return nil, fmt.Errorf("line %d: unknown flag: %s", lineno, flag)
+				}
+			}
+		}
+
+		expr := parts[0]
+		if caseInsensitive {
+			expr = "(?i)" + expr
+		}
+
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid pattern: %s", lineno, err)
+		}
+
+		substs = append(substs, Substitution{
+			Pattern:     pattern,
+			Replacement: parts[1],
+			Lookup:      lookup,
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return substs, nil
+}
+
+var _ WordHandler = SubstHandler{}
+
+// SubstHandler applies substitution rules to a word before passing it
+// to another word handler. For instance, this can be used to normalize
+// unknown words before they are handled by a SuffixHandler.
+type SubstHandler struct {
+	handler       WordHandler
+	substitutions []Substitution
+}
+
+// NewSubstHandler constructs a SubstHandler from a word handler and
+// substitution rules.
+func NewSubstHandler(handler WordHandler, substitutions []Substitution) SubstHandler {
+	return SubstHandler{
+		handler:       handler,
+		substitutions: substitutions,
+	}
+}
+
+// TagProbs returns P(w|t) for a particular word 'w'. All substitutions
+// are applied to the word, if the result is empty, the original word is
+// used.
+func (h SubstHandler) TagProbs(word string) map[model.Tag]float64 {
+	substWord := word
+	for _, subst := range h.substitutions {
+		substWord = subst.Pattern.ReplaceAllString(substWord, subst.Replacement)
+	}
+
+	if len(substWord) == 0 {
+		substWord = word
+	}
+
+	return h.handler.TagProbs(substWord)
+}
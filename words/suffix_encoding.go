@@ -0,0 +1,169 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package words
+
+import (
+	"bytes"
+	"encoding/gob"
+
+	"github.com/danieldk/citar/model"
+)
+
+var _ gob.GobEncoder = SuffixHandler{}
+var _ gob.GobDecoder = &SuffixHandler{}
+var _ gob.GobEncoder = LookupSuffixHandler{}
+var _ gob.GobDecoder = &LookupSuffixHandler{}
+
+// Word classes contain compiled regular expressions and Unicode tables,
+// so the handlers store their configuration. The word classes are compiled
+// again from the configuration after decoding.
+
+type encodedSuffixHandler struct {
+	Config       SuffixHandlerConfig
+	UnigramFreqs map[model.Unigram]int
+	Trees        []encodedSuffixTree
+}
+
+type encodedSuffixTree struct {
+	Root      *encodedTreeNode
+	MaxLength int
+	Theta     float64
+	Prefix    bool
+}
+
+type encodedTreeNode struct {
+	Children map[rune]*encodedTreeNode
+	TagFreqs map[model.Tag]int
+	TagFreq  int
+}
+
+type encodedLookupSuffixHandler struct {
+	Config    SuffixHandlerConfig
+	Probs     []map[string]map[model.Tag]float64
+	MaxLength int
+}
+
+// GobEncode encodes a SuffixHandler as a gob.
+func (h SuffixHandler) GobEncode() ([]byte, error) {
+	eh := encodedSuffixHandler{
+		Config: h.config,
+		Trees:  make([]encodedSuffixTree, len(h.trees)),
+	}
+
+	for i, t := range h.trees {
+		// The unigram frequencies are shared by all trees.
+		eh.UnigramFreqs = t.unigramFreqs
+
+		eh.Trees[i] = encodedSuffixTree{
+			Root:      encodeTreeNode(t.root),
+			MaxLength: t.maxLength,
+			Theta:     t.theta,
+			Prefix:    t.prefix,
+		}
+	}
+
+	return encodeGob(eh)
+}
+
+// GobDecode decodes a SuffixHandler from a gob.
+func (h *SuffixHandler) GobDecode(data []byte) error {
+	var eh encodedSuffixHandler
+	if err := gob.NewDecoder(bytes.NewBuffer(data)).Decode(&eh); err != nil {
+		return err
+	}
+
+	classes, err := compileWordClasses(eh.Config.wordClasses())
+	if err != nil {
+		return err
+	}
+
+	trees := make([]*wordSuffixTree, len(eh.Trees))
+	for i, et := range eh.Trees {
+		trees[i] = &wordSuffixTree{
+			unigramFreqs: eh.UnigramFreqs,
+			root:         decodeTreeNode(et.Root),
+			maxLength:    et.MaxLength,
+			theta:        et.Theta,
+			prefix:       et.Prefix,
+		}
+	}
+
+	h.config = eh.Config
+	h.classes = classes
+	h.trees = trees
+	h.maxTags = eh.Config.MaxTags
+
+	return nil
+}
+
+// GobEncode encodes a LookupSuffixHandler as a gob.
+func (h LookupSuffixHandler) GobEncode() ([]byte, error) {
+	return encodeGob(encodedLookupSuffixHandler{
+		Config:    h.config,
+		Probs:     h.probs,
+		MaxLength: h.maxLength,
+	})
+}
+
+// GobDecode decodes a LookupSuffixHandler from a gob.
+func (h *LookupSuffixHandler) GobDecode(data []byte) error {
+	var eh encodedLookupSuffixHandler
+	if err := gob.NewDecoder(bytes.NewBuffer(data)).Decode(&eh); err != nil {
+		return err
+	}
+
+	classes, err := compileWordClasses(eh.Config.wordClasses())
+	if err != nil {
+		return err
+	}
+
+	h.config = eh.Config
+	h.classes = classes
+	h.probs = eh.Probs
+	h.maxLength = eh.MaxLength
+
+	return nil
+}
+
+func encodeGob(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func encodeTreeNode(n *treeNode) *encodedTreeNode {
+	en := &encodedTreeNode{
+		Children: make(map[rune]*encodedTreeNode),
+		TagFreqs: n.tagFreqs,
+		TagFreq:  n.tagFreq,
+	}
+
+	for r, child := range n.children {
+		en.Children[r] = encodeTreeNode(child)
+	}
+
+	return en
+}
+
+func decodeTreeNode(en *encodedTreeNode) *treeNode {
+	n := newTreeNode()
+	if en == nil {
+		return n
+	}
+
+	for tag, freq := range en.TagFreqs {
+		n.tagFreqs[tag] = freq
+	}
+	n.tagFreq = en.TagFreq
+
+	for r, child := range en.Children {
+		n.children[r] = decodeTreeNode(child)
+	}
+
+	return n
+}
@@ -5,11 +5,10 @@
 package words
 
 import (
+	"fmt"
 	"math"
-	"regexp"
+	"reflect"
 	"sort"
-	"strings"
-	"unicode"
 
 	"github.com/danieldk/citar/model"
 )
@@ -19,16 +18,16 @@ var _ WordHandler = SuffixHandler{}
 // SuffixHandler is an emission probability estimator that uses word suffices.
 // It is normally used for words that were not seen in the training model.
 //
-// Internally, this estimator uses four different distributions based properties
-// of the token: (1) Tokens that start with an uppercase letter; (2) tokens that
-// contain a dash (currently only '-'); (3) tokens that are recognized as
-// cardinals; and (4) remaining tokens (typically lowercase words).
+// Internally, this estimator uses a separate distribution for each word
+// class. By default, there are four classes based on properties of the
+// token: (1) Tokens that start with an uppercase letter; (2) tokens that
+// are recognized as cardinals; (3) tokens that contain a dash (currently
+// only '-'); and (4) remaining tokens (typically lowercase words).
 type SuffixHandler struct {
-	upperTree    *wordSuffixTree
-	lowerTree    *wordSuffixTree
-	dashTree     *wordSuffixTree
-	cardinalTree *wordSuffixTree
-	maxTags      int
+	config  SuffixHandlerConfig
+	classes []wordClass
+	trees   []*wordSuffixTree
+	maxTags int
 }
 
 // SuffixHandlerConfig stores the configuration for a SuffixHandler. It
@@ -43,13 +42,24 @@ type SuffixHandler struct {
 // the various types of tokens depends on the size of the training corpus -
 // the distribution of unknown words is typically closer to that of
 // low-frequency words than high-frequency words.
+//
+// Theta is the weight of the probability distributions of shorter suffixes
+// when estimating the distribution of a suffix. If Theta is zero, it is
+// estimated from the unigram tag distribution (Brants, 2000).
+//
+// WordClasses is an ordered list of word classes, a word belongs to the
+// first class that it matches. If WordClasses is empty, the default classes
+// (upper, cardinal, dash, and lower) are used with the maximum frequencies
+// UpperMaxFreq, CardinalMaxFreq, DashMaxFreq, and LowerMaxFreq.
 type SuffixHandlerConfig struct {
-	MaxSuffixLen    int
-	UpperMaxFreq    int
-	LowerMaxFreq    int
-	DashMaxFreq     int
-	CardinalMaxFreq int
-	MaxTags         int
+	MaxSuffixLen    int               `toml:"max_suffix_len"`
+	UpperMaxFreq    int               `toml:"upper_max_freq"`
+	LowerMaxFreq    int               `toml:"lower_max_freq"`
+	DashMaxFreq     int               `toml:"dash_max_freq"`
+	CardinalMaxFreq int               `toml:"cardinal_max_freq"`
+	MaxTags         int               `toml:"max_tags"`
+	Theta           float64           `toml:"theta"`
+	WordClasses     []WordClassConfig `toml:"word_class"`
 }
 
 // DefaultSuffixHandlerConfig returns a SuffixHandlerConfig that works reasonably
@@ -65,32 +75,114 @@ func DefaultSuffixHandlerConfig() SuffixHandlerConfig {
 	}
 }
 
+// Validate checks that the configuration is valid.
+func (c SuffixHandlerConfig) Validate() error {
+	if c.MaxSuffixLen < 1 {
+		return fmt.Errorf("maximum suffix length should be at least 1: %d", c.MaxSuffixLen)
+	}
+
+	if c.MaxTags < 1 {
+		return fmt.Errorf("maximum number of tags should be at least 1: %d", c.MaxTags)
+	}
+
+	if c.UpperMaxFreq < 0 || c.LowerMaxFreq < 0 || c.DashMaxFreq < 0 || c.CardinalMaxFreq < 0 {
+		return fmt.Errorf("maximum frequencies should not be negative")
+	}
+
+	if c.Theta < 0 || math.IsNaN(c.Theta) || math.IsInf(c.Theta, 0) {
+		return fmt.Errorf("theta should be a non-negative number: %f", c.Theta)
+	}
+
+	_, err := compileWordClasses(c.wordClasses())
+	return err
+}
+
+// Equal returns true if the configurations construct the same suffix
+// handler. Configurations without word classes are compared using the
+// default word classes, so that they are equal to configurations that
+// list the default classes explicitly.
+func (c SuffixHandlerConfig) Equal(other SuffixHandlerConfig) bool {
+	return reflect.DeepEqual(c.normalized(), other.normalized())
+}
+
+// normalized returns the configuration with the word classes that are
+// used by the handler. The maximum frequencies of the default classes are
+// cleared, since they are part of the word classes.
+func (c SuffixHandlerConfig) normalized() SuffixHandlerConfig {
+	c.WordClasses = c.wordClasses()
+	c.UpperMaxFreq = 0
+	c.LowerMaxFreq = 0
+	c.DashMaxFreq = 0
+	c.CardinalMaxFreq = 0
+	return c
+}
+
+func (c SuffixHandlerConfig) wordClasses() []WordClassConfig {
+	if len(c.WordClasses) == 0 {
+		return defaultWordClasses(c)
+	}
+
+	return c.WordClasses
+}
+
 // NewSuffixHandler constructs a new SuffixHandler from the given configuration
-// and model.
+// and model. NewSuffixHandler panics when the configuration is invalid, use
+// SuffixHandlerConfig.Validate to check the configuration.
 func NewSuffixHandler(config SuffixHandlerConfig, m model.Model) SuffixHandler {
-	skip := make(map[uint]interface{})
+	classes, err := compileWordClasses(config.wordClasses())
+	if err != nil {
+		panic(fmt.Sprintf("invalid suffix handler configuration: %s", err))
+	}
 
-	skip[m.TagNumberer().Number(model.StartToken)] = nil
-	skip[m.TagNumberer().Number(model.EndToken)] = nil
+	skip := unknownWordSkipTags(m)
 
-	// An unknown word guesser should not use closed-class tags.
-	for tag := range m.ClosedClassTags() {
-		skip[m.TagNumberer().Number(tag)] = nil
+	theta := config.Theta
+	if theta == 0 {
+		theta = calcTheta(m.UnigramFreqs(), skip)
 	}
 
-	theta := calcTheta(m.UnigramFreqs(), skip)
+	return SuffixHandler{
+		config:  config,
+		classes: classes,
+		trees:   newClassTrees(m, classes, skip, theta, config.MaxSuffixLen, false),
+		maxTags: config.MaxTags,
+	}
+}
+
+// unknownWordSkipTags returns the tags that an unknown word guesser should
+// not use: the sentence boundary tags and closed-class tags.
+func unknownWordSkipTags(m model.Model) map[uint]interface{} {
+	skip := make(map[uint]interface{})
+
+	if tag, ok := m.TagNumberer().Lookup(model.StartToken); ok {
+		skip[tag] = nil
+	}
+	if tag, ok := m.TagNumberer().Lookup(model.EndToken); ok {
+		skip[tag] = nil
+	}
+
+	// An unknown word guesser should not use closed-class tags. If
+	// capitalization is modelled using prefixes, both variants of a tag
+	// are skipped. If the tags have features, all tags with a closed-class
+	// part-of-speech are skipped.
+	for number := 0; number < m.TagNumberer().Size(); number++ {
+		if m.IsClosedClassTag(model.Tag{Tag: uint(number)}) {
+			skip[uint(number)] = nil
+		}
+	}
 
-	upperTree := newWordSuffixTree(m.UnigramFreqs(), skip, theta, config.MaxSuffixLen)
-	lowerTree := newWordSuffixTree(m.UnigramFreqs(), skip, theta, config.MaxSuffixLen)
-	dashTree := newWordSuffixTree(m.UnigramFreqs(), skip, theta, config.MaxSuffixLen)
-	cardinalTree := newWordSuffixTree(m.UnigramFreqs(), skip, theta, config.MaxSuffixLen)
+	return skip
+}
 
-	sh := SuffixHandler{
-		upperTree:    upperTree,
-		lowerTree:    lowerTree,
-		dashTree:     dashTree,
-		cardinalTree: cardinalTree,
-		maxTags:      config.MaxTags,
+// newClassTrees constructs a suffix (or prefix) tree for each word class,
+// using the words in the model that do not exceed the maximum frequency
+// of their class.
+func newClassTrees(m model.Model, classes []wordClass, skip map[uint]interface{},
+	theta float64, maxLength int, prefix bool) []*wordSuffixTree {
+	trees := make([]*wordSuffixTree, len(classes))
+	for i := range classes {
+		trees[i] = newWordSuffixTree(m.UnigramFreqs(), skip, theta, maxLength)
+		trees[i].prefix = prefix
 	}
 
 	for word, tagFreqs := range m.WordTagFreqs() {
@@ -108,12 +200,13 @@ func NewSuffixHandler(config SuffixHandlerConfig, m model.Model) SuffixHandler {
 			wordFreq += tagFreq
 		}
 
-		if t := sh.selectSuffixTreeWithCutoffs(config, word, wordFreq); t != nil {
-			t.addWord(word, tagFreqs, skip)
+		idx := selectWordClass(classes, word)
+		if wordFreq <= classes[idx].maxFreq {
+			trees[idx].addWord(word, tagFreqs, skip)
 		}
 	}
 
-	return sh
+	return trees
 }
 
 type tagProb struct {
@@ -128,47 +221,19 @@ func (h SuffixHandler) TagProbs(word string) map[model.Tag]float64 {
 	return bestNLogSpace(t.suffixTagProbs(word), h.maxTags)
 }
 
-func (h SuffixHandler) selectSuffixTree(word string) *wordSuffixTree {
-	runes := []rune(word)
-
-	var t *wordSuffixTree
-	if unicode.IsUpper(runes[0]) {
-		t = h.upperTree
-	} else if cardinalPattern.MatchString(word) {
-		t = h.cardinalTree
-	} else if strings.ContainsRune(word, '-') {
-		t = h.dashTree
-	} else {
-		t = h.lowerTree
-	}
-
-	return t
+// Config returns the configuration of the handler.
+func (h SuffixHandler) Config() SuffixHandlerConfig {
+	return h.config
 }
 
-func (h SuffixHandler) selectSuffixTreeWithCutoffs(config SuffixHandlerConfig, word string,
-	wordFreq int) *wordSuffixTree {
-	runes := []rune(word)
-
-	var t *wordSuffixTree
-	if unicode.IsUpper(runes[0]) {
-		if wordFreq <= config.UpperMaxFreq {
-			t = h.upperTree
-		}
-	} else if cardinalPattern.MatchString(word) {
-		if wordFreq <= config.CardinalMaxFreq {
-			t = h.cardinalTree
-		}
-	} else if strings.ContainsRune(word, '-') {
-		if wordFreq <= config.DashMaxFreq {
-			t = h.dashTree
-		}
-	} else {
-		if wordFreq <= config.LowerMaxFreq {
-			t = h.lowerTree
-		}
-	}
+// WordClass returns the name of the word class that is used to estimate
+// the emission probabilities of a word.
+func (h SuffixHandler) WordClass(word string) string {
+	return h.classes[selectWordClass(h.classes, word)].name
+}
 
-	return t
+func (h SuffixHandler) selectSuffixTree(word string) *wordSuffixTree {
+	return h.trees[selectWordClass(h.classes, word)]
 }
 
 func calcTheta(uf map[model.Unigram]int, skip map[uint]interface{}) float64 {
@@ -214,23 +279,26 @@ var _ WordHandler = LookupSuffixHandler{}
 // The initial construction of a LookupSuffixHandler takes a small amount
 // of extra time. However, it is much faster during taggin.
 type LookupSuffixHandler struct {
-	upperProbs    map[string]map[model.Tag]float64
-	lowerProbs    map[string]map[model.Tag]float64
-	dashProbs     map[string]map[model.Tag]float64
-	cardinalProbs map[string]map[model.Tag]float64
-	maxLength     int
+	config    SuffixHandlerConfig
+	classes   []wordClass
+	probs     []map[string]map[model.Tag]float64
+	maxLength int
 }
 
 // NewLookupSuffixHandler constructs a LookupSuffixHandler from a
 // SuffixHandler. After construction, the SuffixHandler is discarded
 // after construction.
 func NewLookupSuffixHandler(sh SuffixHandler) LookupSuffixHandler {
+	probs := make([]map[string]map[model.Tag]float64, len(sh.trees))
+	for i, t := range sh.trees {
+		probs[i] = convertTree(t, sh.maxTags)
+	}
+
 	return LookupSuffixHandler{
-		upperProbs:    convertTree(sh.upperTree, sh.maxTags),
-		lowerProbs:    convertTree(sh.lowerTree, sh.maxTags),
-		dashProbs:     convertTree(sh.dashTree, sh.maxTags),
-		cardinalProbs: convertTree(sh.cardinalTree, sh.maxTags),
-		maxLength:     sh.lowerTree.maxLength,
+		config:    sh.config,
+		classes:   sh.classes,
+		probs:     probs,
+		maxLength: sh.trees[0].maxLength,
 	}
 }
 
@@ -255,18 +323,20 @@ func (h LookupSuffixHandler) TagProbs(word string) map[model.Tag]float64 {
 	return m[string(runes)]
 }
 
-func (h LookupSuffixHandler) selectMap(word string) map[string]map[model.Tag]float64 {
-	runes := []rune(word)
+// Config returns the configuration of the SuffixHandler that the handler
+// was constructed from.
+func (h LookupSuffixHandler) Config() SuffixHandlerConfig {
+	return h.config
+}
 
-	if unicode.IsUpper(runes[0]) {
-		return h.upperProbs
-	} else if cardinalPattern.MatchString(word) {
-		return h.cardinalProbs
-	} else if strings.ContainsRune(word, '-') {
-		return h.dashProbs
-	}
+// WordClass returns the name of the word class that is used to estimate
+// the emission probabilities of a word.
+func (h LookupSuffixHandler) WordClass(word string) string {
+	return h.classes[selectWordClass(h.classes, word)].name
+}
 
-	return h.lowerProbs
+func (h LookupSuffixHandler) selectMap(word string) map[string]map[model.Tag]float64 {
+	return h.probs[selectWordClass(h.classes, word)]
 }
 
 func convertTree(t *wordSuffixTree, maxTags int) map[string]map[model.Tag]float64 {
@@ -310,6 +380,16 @@ func convertTreeRecursive(n *treeNode, maxTags int, theta float64,
 }
 
 func bestNLogSpace(tp map[model.Tag]float64, n int) map[model.Tag]float64 {
+	results := bestN(tp, n)
+	for tag, prob := range results {
+		results[tag] = math.Log(prob)
+	}
+
+	return results
+}
+
+// bestN returns the n tags with the highest probabilities.
+func bestN(tp map[model.Tag]float64, n int) map[model.Tag]float64 {
 	sorted := make([]tagProb, 0, n)
 
 	for tag, prob := range tp {
@@ -323,9 +403,8 @@ func bestNLogSpace(tp map[model.Tag]float64, n int) map[model.Tag]float64 {
 	}
 
 	results := make(map[model.Tag]float64)
-
 	for _, tagProb := range sorted {
-		results[tagProb.tag] = math.Log(tagProb.prob)
+		results[tagProb.tag] = tagProb.prob
 	}
 
 	return results
@@ -340,5 +419,3 @@ func copyTagProbs(tp map[model.Tag]float64) map[model.Tag]float64 {
 
 	return tpc
 }
-
-var cardinalPattern = regexp.MustCompile(`^([0-9]+)|([0-9]+\.)|([0-9.,:-]+[0-9]+)|([0-9]+[a-zA-Z]{1,3})$`)
@@ -6,11 +6,14 @@ package words
 
 import "github.com/danieldk/citar/model"
 
+// wordSuffixTree stores tag frequencies of word suffixes. If prefix is
+// set, the tree stores word prefixes instead.
 type wordSuffixTree struct {
 	unigramFreqs map[model.Unigram]int
 	root         *treeNode
 	maxLength    int
 	theta        float64
+	prefix       bool
 }
 
 func newWordSuffixTree(unigramFreqs map[model.Unigram]int,
@@ -36,22 +39,26 @@ func newWordSuffixTree(unigramFreqs map[model.Unigram]int,
 	}
 }
 
-func (t wordSuffixTree) addWord(word string, tf map[model.Tag]int, skip map[uint]interface{}) {
+// affix returns the runes of the suffix (or prefix) of a word, in the
+// order in which they are stored in the tree.
+func (t wordSuffixTree) affix(word string) []rune {
 	runes := []rune(word)
-	reverse(runes)
+	if !t.prefix {
+		reverse(runes)
+	}
 	if len(runes) > t.maxLength {
 		runes = runes[0:t.maxLength]
 	}
 
-	t.root.addSuffix(runes, tf, skip)
+	return runes
+}
+
+func (t wordSuffixTree) addWord(word string, tf map[model.Tag]int, skip map[uint]interface{}) {
+	t.root.addSuffix(t.affix(word), tf, skip)
 }
 
 func (t wordSuffixTree) suffixTagProbs(word string) map[model.Tag]float64 {
-	runes := []rune(word)
-	reverse(runes)
-	if len(runes) > t.maxLength {
-		runes = runes[0:t.maxLength]
-	}
+	runes := t.affix(word)
 
 	tagProbs := make(map[model.Tag]float64)
 	for tag := range t.root.tagFreqs {
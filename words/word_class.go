@@ -0,0 +1,160 @@
+// Copyright 2016 The Citar Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package words
+
+import (
+	"fmt"
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+)
+
+// WordClassConfig describes a class of words for which the suffix handlers
+// use a separate suffix distribution. A word belongs to a class when it
+// matches Pattern (a regular expression) and its first character is in
+// the Unicode category or script FirstCategory (e.g. "Lu" or "Greek").
+// Conditions that are not specified always match. Words with a training
+// frequency higher than MaxFreq are not used to estimate the suffix
+// distribution of the class.
+type WordClassConfig struct {
+	Name          string `toml:"name"`
+	Pattern       string `toml:"pattern"`
+	FirstCategory string `toml:"first_category"`
+	MaxFreq       int    `toml:"max_freq"`
+}
+
+const defaultCardinalPattern = `^([0-9]+)|([0-9]+\.)|([0-9.,:-]+[0-9]+)|([0-9]+[a-zA-Z]{1,3})$`
+
+// defaultWordClasses returns the word classes of Brants (2000): tokens
+// that start with an uppercase letter, cardinals, tokens that contain a
+// dash, and remaining tokens.
+func defaultWordClasses(c SuffixHandlerConfig) []WordClassConfig {
+	return []WordClassConfig{
+		{Name: "upper", FirstCategory: "Lu", MaxFreq: c.UpperMaxFreq},
+		{Name: "cardinal", Pattern: defaultCardinalPattern, MaxFreq: c.CardinalMaxFreq},
+		{Name: "dash", Pattern: "-", MaxFreq: c.DashMaxFreq},
+		{Name: "lower", MaxFreq: c.LowerMaxFreq},
+	}
+}
+
+type wordClass struct {
+	name          string
+	pattern       *regexp.Regexp
+	firstCategory *unicode.RangeTable
+	maxFreq       int
+}
+
+func (c wordClass) matches(word string) bool {
+	if c.firstCategory != nil {
+		first, _ := utf8.DecodeRuneInString(word)
+		if !unicode.Is(c.firstCategory, first) {
+			return false
+		}
+	}
+
+	return c.pattern == nil || c.pattern.MatchString(word)
+}
+
+// compileWordClasses compiles word class configurations. The last class
+// must match all words, so that every word belongs to a class.
+func compileWordClasses(configs []WordClassConfig) ([]wordClass, error) {
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no word classes")
+	}
+
+	names := make(map[string]interface{})
+	classes := make([]wordClass, 0, len(configs))
+
+	for _, config := range configs {
+		if config.Name == "" {
+			return nil, fmt.Errorf("word class without a name")
+		}
+
+		if _, ok := names[config.Name]; ok {
+			return nil, fmt.Errorf("duplicate word class: %s", config.Name)
+		}
+		names[config.Name] = nil
+
+		if config.MaxFreq < 0 {
+			return nil, fmt.Errorf("maximum frequency of word class %s should not be negative", config.Name)
+		}
+
+		class := wordClass{name: config.Name, maxFreq: config.MaxFreq}
+
+		if config.Pattern != "" {
+			pattern, err := regexp.Compile(config.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern for word class %s: %s", config.Name, err)
+			}
+			class.pattern = pattern
+		}
+
+		if config.FirstCategory != "" {
+			table, ok := unicode.Categories[config.FirstCategory]
+			if !ok {
+				table, ok = unicode.Scripts[config.FirstCategory]
+			}
+			if !ok {
+				return nil, fmt.Errorf("unknown Unicode category or script for word class %s: %s",
+					config.Name, config.FirstCategory)
+			}
+			class.firstCategory = table
+		}
+
+		classes = append(classes, class)
+	}
+
+	last := classes[len(classes)-1]
+	if last.pattern != nil || last.firstCategory != nil {
+		return nil, fmt.Errorf("the last word class (%s) should match all words", last.name)
+	}
+
+	return classes, nil
+}
+
+// selectWordClass returns the index of the first class that the word
+// belongs to.
+func selectWordClass(classes []wordClass, word string) int {
+	for i, class := range classes {
+		if class.matches(word) {
+			return i
+		}
+	}
+
+	// Unreachable for validated classes, the last class matches all words.
+	return len(classes) - 1
+}
+
+// WordClassifier assigns words to the word classes of a suffix handler
+// configuration, without constructing the suffix trees of the classes.
+type WordClassifier struct {
+	classes []wordClass
+}
+
+// NewWordClassifier constructs a WordClassifier for the word classes of
+// a suffix handler configuration.
+func NewWordClassifier(config SuffixHandlerConfig) (*WordClassifier, error) {
+	classes, err := compileWordClasses(config.wordClasses())
+	if err != nil {
+		return nil, err
+	}
+
+	return &WordClassifier{classes: classes}, nil
+}
+
+// WordClass returns the name of the class that the word belongs to.
+func (c *WordClassifier) WordClass(word string) string {
+	return c.classes[selectWordClass(c.classes, word)].name
+}
+
+// Classes returns the names of the word classes.
+func (c *WordClassifier) Classes() []string {
+	names := make([]string, len(c.classes))
+	for i, class := range c.classes {
+		names[i] = class.name
+	}
+
+	return names
+}
@@ -11,3 +11,13 @@ import "github.com/danieldk/citar/model"
 type WordHandler interface {
 	TagProbs(word string) map[model.Tag]float64
 }
+
+// capitalVariant returns the variant of a tag that occurs in the model,
+// preferring the variant with the given capitalization.
+func capitalVariant(m model.Model, tag model.Tag, capital bool) (model.Tag, bool) {
+	if variant, ok := m.CapitalVariant(tag, capital); ok {
+		return variant, true
+	}
+
+	return m.CapitalVariant(tag, !capital)
+}